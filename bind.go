@@ -0,0 +1,628 @@
+package query
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Placeholder identifies the bind parameter style used by a database driver. It is used when named parameters are
+// resolved to ordinal positions.
+type Placeholder int
+
+// The available placeholder styles.
+const (
+	// Question identifies placeholders of the form ?, used by SQLite and MySQL drivers.
+	Question Placeholder = iota
+	// Dollar identifies placeholders of the form $1, used by PostgreSQL drivers.
+	Dollar
+)
+
+// placeholder identifies a bind parameter found within a SQL expression. The start and end values are the byte
+// offsets of the placeholder token within the expression. Named parameters provide the name, numbered parameters
+// provide the ordinal, and question mark parameters provide neither.
+type placeholder struct {
+	start, end int
+	name       string
+	ordinal    int
+}
+
+// placeholders returns the placeholders found in the supplied SQL expression. Quoted strings and identifiers are
+// skipped, as are PostgreSQL style type casts (e.g. "id::text").
+func placeholders(expr string) []placeholder {
+	var found []placeholder
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; c {
+		case '\'', '"', '`':
+			for i++; i < len(expr) && expr[i] != c; i++ {
+			}
+		case '?':
+			found = append(found, placeholder{start: i, end: i + 1})
+		case '$':
+			end := i + 1
+			for end < len(expr) && expr[end] >= '0' && expr[end] <= '9' {
+				end++
+			}
+			if end > i+1 {
+				ordinal, _ := strconv.Atoi(expr[i+1 : end])
+				found = append(found, placeholder{start: i, end: end, ordinal: ordinal})
+				i = end - 1
+			}
+		case ':':
+			if i+1 < len(expr) && expr[i+1] == ':' {
+				i++
+				continue
+			}
+			end := i + 1
+			for end < len(expr) && isIdent(expr[end], end == i+1) {
+				end++
+			}
+			if end > i+1 {
+				found = append(found, placeholder{start: i, end: end, name: expr[i+1 : end]})
+				i = end - 1
+			}
+		}
+	}
+	return found
+}
+
+// isIdent returns true if the supplied character may be used within a parameter name. Digits may not be used as the
+// first character.
+func isIdent(c byte, first bool) bool {
+	switch {
+	case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return true
+	case c >= '0' && c <= '9':
+		return !first
+	}
+	return false
+}
+
+// namedArgs returns the named arguments, provided using [sql.Named], found in the supplied arguments.
+func namedArgs(args []any) map[string]any {
+	var named map[string]any
+	for _, arg := range args {
+		if arg, ok := arg.(sql.NamedArg); ok {
+			if named == nil {
+				named = make(map[string]any)
+			}
+			named[arg.Name] = arg.Value
+		}
+	}
+	return named
+}
+
+// isNull returns true if the supplied value would be sent to the database as NULL. A nil slice is treated as null,
+// rather than as an empty list, so that an [Optional] condition bound to it is removed.
+func isNull(v any) bool {
+	if v == nil {
+		return true
+	}
+	switch val := reflect.ValueOf(v); val.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Func, reflect.Chan, reflect.Slice:
+		if val.IsNil() {
+			return true
+		}
+	}
+	if valuer, ok := v.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		return err == nil && value == nil
+	}
+	return false
+}
+
+// bind returns the SQL query for the statement and the arguments to send with it. [Modifier] arguments are applied to
+// the statement and [Optional] conditions are removed from the statement when their named arguments are absent or
+// null. The statement is then passed to the rewrite function of the [Transaction], if implemented. The supplied
+// statement is updated to hold the result, so that the bindings of the query are taken from the rewritten statement.
+// Named parameters are resolved to ordinal placeholders using the placeholder style of the [Transaction], and slices
+// bound to IN lists are expanded into a placeholder for each element. An error is returned if a positional argument is
+// not referenced by a placeholder.
+//
+// If a derive function is supplied, the query is produced from the statement returned by the function. When a derive
+// function or a [Modifier] is supplied, the positional placeholders of the statement are bound before the statement
+// is modified so that the arguments remain bound to the same expressions when parts of the statement are removed or
+// replaced, such as a [Limit] replaced by [LimitTo].
+func bind(stmt *statement, tx Transaction, args []any, derive func(statement) statement) (string, []any, error) {
+	style := bindWith(tx)
+	if derive != nil || hasModifier(args) {
+		var err error
+		if args, style, err = normalize(stmt, style, args); err != nil {
+			return "", nil, err
+		}
+	}
+	declared := paramNames(stmt)
+	args = modify(stmt, args)
+	named := namedArgs(args)
+	stmt.prune(func(name string) bool {
+		v, ok := named[name]
+		return ok && !isNull(v)
+	})
+	if bound := rewrite(stmt, tx); len(bound) > 0 {
+		args = append(args[:len(args):len(args)], bound...)
+		named = namedArgs(args)
+	}
+	query := stmt.SQL()
+	if derive != nil {
+		derived := derive(*stmt)
+		query = derived.SQL()
+	}
+	if named == nil && !hasList(args) {
+		if err := unusedArgs(referencedArgs(query, len(args))); err != nil {
+			return "", nil, err
+		}
+		return query, args, nil
+	}
+	return resolve(query, style, withoutNamed(args, declared), named)
+}
+
+// paramNames returns the names of the named parameters referenced by the statement.
+func paramNames(stmt *statement) map[string]bool {
+	names := make(map[string]bool)
+	stmt.walk(func(expr *string) {
+		for _, p := range placeholders(*expr) {
+			if p.name != "" {
+				names[p.name] = true
+			}
+		}
+	})
+	return names
+}
+
+// withoutNamed returns the arguments with the named arguments of the supplied names removed. The named arguments
+// remaining are those which are not bound by the statement, and are sent to the driver when not referenced by the
+// query.
+func withoutNamed(args []any, names map[string]bool) []any {
+	kept := make([]any, 0, len(args))
+	for _, arg := range args {
+		if arg, ok := arg.(sql.NamedArg); ok && names[arg.Name] {
+			continue
+		}
+		kept = append(kept, arg)
+	}
+	return kept
+}
+
+// rewrite passes the statement to the rewrite function of the [Transaction], if implemented, and replaces the
+// statement with the result. The named arguments binding the Args of the rewritten conditions are returned.
+func rewrite(stmt *statement, tx Transaction) []any {
+	fn := rewriteWith(tx)
+	if fn == nil {
+		return nil
+	}
+	st := stmt.export()
+	fn(&st)
+	var args []any
+	*stmt, args = st.statement()
+	return args
+}
+
+// normalize rewrites the positional placeholders of the statement to named parameters and returns the arguments
+// with the positional arguments replaced by the matching named arguments. Named arguments and modifiers are returned
+// unchanged. The placeholder style of the positional placeholders is returned, or the supplied style if the statement
+// does not contain positional placeholders. An error is returned if a positional argument is not referenced by the
+// statement.
+func normalize(stmt *statement, style Placeholder, args []any) ([]any, Placeholder, error) {
+	positional := make([]any, 0, len(args))
+	normalized := make([]any, 0, len(args))
+	for _, arg := range args {
+		switch arg.(type) {
+		case sql.NamedArg, Modifier:
+			normalized = append(normalized, arg)
+			continue
+		}
+		positional = append(positional, arg)
+	}
+
+	found := false
+	next := 0
+	used := make([]bool, len(positional))
+	stmt.walk(func(expr *string) {
+		var (
+			w    strings.Builder
+			last int
+		)
+		for _, p := range placeholders(*expr) {
+			if p.name != "" {
+				continue
+			}
+			if !found {
+				style, found = placeholderStyle([]placeholder{p}, style), true
+			}
+			idx := next
+			if p.ordinal > 0 {
+				idx = p.ordinal - 1
+			} else {
+				next++
+			}
+			if idx >= len(positional) {
+				continue
+			}
+			used[idx] = true
+			w.WriteString((*expr)[last:p.start])
+			fmt.Fprintf(&w, ":_arg%d", idx+1)
+			last = p.end
+		}
+		if last > 0 {
+			w.WriteString((*expr)[last:])
+			*expr = w.String()
+		}
+	})
+	for i, arg := range positional {
+		normalized = append(normalized, sql.Named(fmt.Sprintf("_arg%d", i+1), arg))
+	}
+	return normalized, style, unusedArgs(used)
+}
+
+// resolve rewrites the placeholders of the supplied query to ordinal placeholders and returns the arguments in the
+// matching order. Named parameters are bound to the named arguments and the remaining placeholders are bound to the
+// positional arguments. If the query contains positional placeholders their style is retained, otherwise the
+// supplied style is used. An error is returned if a positional argument is not referenced by the query.
+// Slices bound to the sole placeholder of an IN list are expanded into a placeholder for each element. Named arguments
+// which are not referenced by a parameter of the query, such as those bound to "@name" parameters handled by the
+// driver, are sent after the bound arguments.
+func resolve(query string, style Placeholder, args []any, named map[string]any) (string, []any, error) {
+	positional := make([]any, 0, len(args))
+	for _, arg := range args {
+		if _, ok := arg.(sql.NamedArg); !ok {
+			positional = append(positional, arg)
+		}
+	}
+
+	found := placeholders(query)
+	style = placeholderStyle(found, style)
+
+	var (
+		w          strings.Builder
+		bound      = make([]any, 0, len(found))
+		ordinals   = make(map[string]int)
+		referenced = make(map[string]bool)
+		used       = make([]bool, len(positional))
+		next       int
+		last       int
+	)
+	for _, p := range found {
+		var (
+			key   string
+			value any
+		)
+		switch {
+		case p.name != "":
+			v, ok := named[p.name]
+			if !ok {
+				return "", nil, fmt.Errorf("missing argument for parameter %q", p.name)
+			}
+			key, value = ":"+p.name, v
+			referenced[p.name] = true
+		default:
+			idx := next
+			if p.ordinal > 0 {
+				idx = p.ordinal - 1
+			} else {
+				next++
+			}
+			if idx >= len(positional) {
+				return "", nil, fmt.Errorf("missing argument for placeholder %d", idx+1)
+			}
+			key, value = "$"+strconv.Itoa(idx+1), positional[idx]
+			used[idx] = true
+		}
+
+		if isList(value) && inList(query, p) && reflect.ValueOf(value).Len() == 0 {
+			start, end, pred, ok := emptyList(query, p)
+			if !ok || start < last {
+				return "", nil, fmt.Errorf("empty list bound to %s cannot be replaced by a constant predicate", key)
+			}
+			w.WriteString(query[last:start])
+			w.WriteString(pred)
+			last = end
+			continue
+		}
+
+		w.WriteString(query[last:p.start])
+		last = p.end
+		if isList(value) && inList(query, p) {
+			list := reflect.ValueOf(value)
+			for i := 0; i < list.Len(); i++ {
+				if i > 0 {
+					w.WriteString(", ")
+				}
+				bound = writePlaceholder(&w, style, bound, ordinals, key+"["+strconv.Itoa(i)+"]", list.Index(i).Interface())
+			}
+			continue
+		}
+		bound = writePlaceholder(&w, style, bound, ordinals, key, value)
+	}
+	w.WriteString(query[last:])
+
+	if err := unusedArgs(used); err != nil {
+		return "", nil, err
+	}
+	for _, arg := range args {
+		if arg, ok := arg.(sql.NamedArg); ok && !referenced[arg.Name] {
+			bound = append(bound, arg)
+		}
+	}
+	return w.String(), bound, nil
+}
+
+// unusedArgs returns an error listing the positions of the positional arguments which are not used.
+func unusedArgs(used []bool) error {
+	var unused []string
+	for i, ok := range used {
+		if !ok {
+			unused = append(unused, strconv.Itoa(i+1))
+		}
+	}
+	if len(unused) > 0 {
+		return fmt.Errorf("unused positional arguments: %s", strings.Join(unused, ", "))
+	}
+	return nil
+}
+
+// emptyList returns the constant predicate which replaces an IN list predicate, e.g. "id IN (?)", when the
+// placeholder of the list is bound to an empty slice. IN is replaced by "1 = 0" and NOT IN by "1 = 1", as an empty
+// list cannot be written portably. The start and end offsets of the replaced predicate are returned. The operand of
+// the predicate must be an identifier, a function call or a parenthesized expression which is not itself the operand
+// of another operator; false is returned if the predicate cannot be isolated.
+func emptyList(query string, p placeholder) (start, end int, pred string, ok bool) {
+	end = p.end + strings.Index(query[p.end:], ")") + 1
+	before := strings.TrimRight(query[:p.start], " \t\n")
+	before = strings.TrimRight(before[:len(before)-1], " \t\n")
+	before = strings.TrimRight(before[:len(before)-2], " \t\n")
+	pred = "1 = 0"
+	if hasKeyword(before, "NOT") {
+		before = strings.TrimRight(before[:len(before)-3], " \t\n")
+		pred = "1 = 1"
+	}
+
+	start = len(before)
+operand:
+	for start > 0 {
+		switch c := before[start-1]; {
+		case c == ')':
+			depth := 0
+			for start > 0 {
+				start--
+				if before[start] == ')' {
+					depth++
+				} else if before[start] == '(' {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+			if depth != 0 {
+				return 0, 0, "", false
+			}
+		case c == '"' || c == '`':
+			open := strings.LastIndexByte(before[:start-1], c)
+			if open < 0 {
+				return 0, 0, "", false
+			}
+			start = open
+		case c == '.' || isIdent(c, false):
+			start--
+		default:
+			break operand
+		}
+	}
+	if start == len(before) {
+		return 0, 0, "", false
+	}
+	preceding := strings.TrimRight(before[:start], " \t\n")
+	switch {
+	case preceding == "", strings.HasSuffix(preceding, "("), strings.HasSuffix(preceding, ","),
+		hasKeyword(preceding, "AND"), hasKeyword(preceding, "OR"), hasKeyword(preceding, "NOT"):
+		return start, end, pred, true
+	}
+	return 0, 0, "", false
+}
+
+// hasKeyword returns true if the supplied SQL ends with the keyword, ignoring case.
+func hasKeyword(sql, keyword string) bool {
+	n := len(keyword)
+	if len(sql) < n || !strings.EqualFold(sql[len(sql)-n:], keyword) {
+		return false
+	}
+	return len(sql) == n || !isIdent(sql[len(sql)-n-1], false)
+}
+
+// writePlaceholder writes a placeholder for the value using the supplied style and returns the bound arguments. In
+// the dollar style, values with the same key share an ordinal placeholder.
+func writePlaceholder(w *strings.Builder, style Placeholder, bound []any, ordinals map[string]int, key string, value any) []any {
+	if style == Question {
+		w.WriteByte('?')
+		return append(bound, value)
+	}
+	ordinal, ok := ordinals[key]
+	if !ok {
+		bound = append(bound, value)
+		ordinal = len(bound)
+		ordinals[key] = ordinal
+	}
+	w.WriteByte('$')
+	w.WriteString(strconv.Itoa(ordinal))
+	return bound
+}
+
+// isList returns true if the supplied value is a slice to be expanded into a list of placeholders. Byte slices and
+// values implementing [driver.Valuer] are passed to the driver as a single value.
+func isList(v any) bool {
+	if _, ok := v.(driver.Valuer); ok {
+		return false
+	}
+	typ := reflect.TypeOf(v)
+	return typ != nil && typ.Kind() == reflect.Slice && typ.Elem().Kind() != reflect.Uint8
+}
+
+// hasList returns true if any of the supplied arguments is a slice to be expanded into a list of placeholders.
+func hasList(args []any) bool {
+	for _, arg := range args {
+		if named, ok := arg.(sql.NamedArg); ok {
+			arg = named.Value
+		}
+		if isList(arg) {
+			return true
+		}
+	}
+	return false
+}
+
+// inList returns true if the placeholder is the sole element of an IN list, e.g. "id IN (?)".
+func inList(query string, p placeholder) bool {
+	before := strings.TrimRight(query[:p.start], " \t\n")
+	after := strings.TrimLeft(query[p.end:], " \t\n")
+	if !strings.HasSuffix(before, "(") || !strings.HasPrefix(after, ")") {
+		return false
+	}
+	before = strings.TrimRight(before[:len(before)-1], " \t\n")
+	if len(before) < 2 || !strings.EqualFold(before[len(before)-2:], "IN") {
+		return false
+	}
+	return len(before) == 2 || !isIdent(before[len(before)-3], false)
+}
+
+// referencedArgs reports which of the n positional arguments are referenced by the placeholders of the query.
+func referencedArgs(query string, n int) []bool {
+	used := make([]bool, n)
+	next := 0
+	for _, p := range placeholders(query) {
+		idx := next
+		switch {
+		case p.name != "":
+			continue
+		case p.ordinal > 0:
+			idx = p.ordinal - 1
+		default:
+			next++
+		}
+		if idx < n {
+			used[idx] = true
+		}
+	}
+	return used
+}
+
+// positionalCount returns the number of positional arguments referenced by the placeholders of the query. Ordinal
+// placeholders reference the argument at their ordinal position, while the remaining placeholders reference the
+// next argument.
+func positionalCount(query string) int {
+	n, next := 0, 0
+	for _, p := range placeholders(query) {
+		switch {
+		case p.name != "":
+		case p.ordinal > n:
+			n = p.ordinal
+		case p.ordinal == 0:
+			if next++; next > n {
+				n = next
+			}
+		}
+	}
+	return n
+}
+
+// placeholderStyle returns the style of the positional placeholders found in a query, or the fallback style if the
+// query only contains named parameters.
+func placeholderStyle(found []placeholder, fallback Placeholder) Placeholder {
+	for _, p := range found {
+		switch {
+		case p.ordinal > 0:
+			return Dollar
+		case p.name == "":
+			return Question
+		}
+	}
+	return fallback
+}
+
+// paramArgs returns the named arguments described by the supplied parameters. The parameters may be a map with
+// string keys or a struct. Struct fields are named using the "q" struct tag if provided, otherwise the name is
+// inferred from the field name using the [Namer]. Embedded struct fields are included as if they were defined by the
+// parent struct.
+func paramArgs(namer Namer, params any) ([]any, error) {
+	val := reflect.ValueOf(params)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, nil
+		}
+		val = val.Elem()
+	}
+
+	switch val.Kind() {
+	case reflect.Invalid:
+		return nil, nil
+	case reflect.Map:
+		if val.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("parameters of type %s must use string keys", val.Type())
+		}
+		args := make([]any, 0, val.Len())
+		iter := val.MapRange()
+		for iter.Next() {
+			args = append(args, sql.Named(iter.Key().String(), iter.Value().Interface()))
+		}
+		return args, nil
+	case reflect.Struct:
+		return structArgs(namer, val), nil
+	default:
+		return nil, fmt.Errorf("parameters of type %s must be a struct or map", val.Type())
+	}
+}
+
+// structArgs returns the named arguments described by the fields of the supplied struct value.
+func structArgs(namer Namer, val reflect.Value) []any {
+	typ := val.Type()
+	args := make([]any, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		fld := typ.Field(i)
+		switch {
+		case fld.Anonymous && fld.Type.Kind() == reflect.Struct:
+			args = append(args, structArgs(namer, val.Field(i))...)
+		case fld.IsExported():
+			name := fld.Tag.Get("q")
+			if name == "" {
+				name = namer.Column(fieldInfo{fld})
+			}
+			args = append(args, sql.Named(name, val.Field(i).Interface()))
+		}
+	}
+	return args
+}
+
+// checkParams returns an error if the named arguments do not match the parameters of the statement. Every named
+// parameter, including those of [Optional] conditions, must be provided and every argument must be referenced.
+// Positional placeholders are not permitted.
+func checkParams(stmt statement, args []any) error {
+	var missing, unused []string
+	referenced := make(map[string]bool)
+	named := namedArgs(args)
+	for _, p := range placeholders(stmt.SQL()) {
+		if p.name == "" {
+			return fmt.Errorf("positional placeholders cannot be bound to named parameters")
+		}
+		if referenced[p.name] {
+			continue
+		}
+		referenced[p.name] = true
+		if _, ok := named[p.name]; !ok {
+			missing = append(missing, p.name)
+		}
+	}
+	for _, arg := range args {
+		if arg, ok := arg.(sql.NamedArg); ok && !referenced[arg.Name] {
+			unused = append(unused, arg.Name)
+		}
+	}
+
+	switch {
+	case len(missing) > 0:
+		return fmt.Errorf("missing arguments for parameters: %s", strings.Join(missing, ", "))
+	case len(unused) > 0:
+		return fmt.Errorf("unused arguments: %s", strings.Join(unused, ", "))
+	}
+	return nil
+}
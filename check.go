@@ -0,0 +1,62 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Check verifies that the query generated for the Source type matches the schema of the database without fetching
+// any rows. The query is wrapped in a query which matches no rows, with a null value bound to each placeholder, and
+// sent to the database. [Optional] conditions are included so that they are also checked. An error is returned if the
+// database rejects the query, such as when a table or column does not exist, or if the result columns do not match
+// the columns of the Source type. Example:
+//
+//	func TestSchema(t *testing.T) {
+//		if err := query.Check[users](context.Background(), db); err != nil {
+//			t.Error(err)
+//		}
+//	}
+//	// Query: SELECT * FROM (SELECT users.id AS c0, users.name AS c1 FROM users) AS checked WHERE (1 = 0)
+func Check[Source any](ctx context.Context, tx Transaction) error {
+	stmt, err := plan[Source](nameWith(tx))
+	if err != nil {
+		return err
+	}
+	stmt.alias()
+	rewrite(&stmt, tx)
+
+	wrapper := statement{
+		columns:    []column{{name: "*"}},
+		table:      "(" + stmt.SQL() + ") AS checked",
+		conditions: []condition{{expr: "1 = 0"}},
+	}
+	query := wrapper.SQL()
+	args := make([]any, positionalCount(query))
+	named := make(map[string]any)
+	for _, p := range placeholders(query) {
+		if p.name != "" {
+			named[p.name] = nil
+		}
+	}
+	if len(named) > 0 {
+		for name := range named {
+			args = append(args, sql.Named(name, nil))
+		}
+		query, args, err = resolve(query, bindWith(tx), args, named)
+		if err != nil {
+			return fail("bind", "", nil, err)
+		}
+	}
+
+	log(tx, query, args)
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fail("check", query, args, err)
+	}
+	defer rows.Close()
+
+	if err := verify(rows, &stmt, query, args); err != nil {
+		return err
+	}
+	return rows.Close()
+}
@@ -0,0 +1,83 @@
+package query
+
+import (
+	"context"
+	"reflect"
+	"strings"
+)
+
+// Count returns the number of results that would be returned by [All] for the Source type. The count query shares the
+// table, joins and conditions of the Source type, but not its columns, ordering, limit and offset. Queries containing
+// a many relationship count the distinct results of the top level table. Example:
+//
+//	type users struct {
+//		query.Conditions `q:"name LIKE ?"`
+//
+//		ID   int
+//		Name string
+//	}
+//	// Query: SELECT COUNT(*) FROM users WHERE (name LIKE ?)
+//	count, _ := query.Count[users](ctx, db, "J%")
+func Count[Source any](ctx context.Context, tx Transaction, args ...any) (int, error) {
+	namer := nameWith(tx)
+	stmt, err := plan[Source](namer)
+	if err != nil {
+		return 0, err
+	}
+	typ := reflect.TypeOf([]Source(nil))
+	many := hasMany(typ.Elem())
+	query, args, err := bind(&stmt, tx, args, func(stmt statement) statement {
+		if many {
+			return stmt.count("COUNT(DISTINCT " + tableRef(stmt.table) + "." + namer.Ident(typ) + ")")
+		}
+		return stmt.count("COUNT(*)")
+	})
+	if err != nil {
+		return 0, fail("bind", "", nil, err)
+	}
+
+	var count int
+	log(tx, query, args)
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fail("count", query, args, err)
+	}
+	return count, nil
+}
+
+// Exists returns true if [All] would return any results for the Source type. Like [Count], the query shares the
+// table, joins and conditions of the Source type. Example:
+//
+//	type users struct {
+//		query.Conditions `q:"name = ?"`
+//
+//		ID int
+//	}
+//	// Query: SELECT EXISTS(SELECT 1 FROM users WHERE (name = ?))
+//	exists, _ := query.Exists[users](ctx, db, "Bob")
+func Exists[Source any](ctx context.Context, tx Transaction, args ...any) (bool, error) {
+	stmt, err := plan[Source](nameWith(tx))
+	if err != nil {
+		return false, err
+	}
+	query, args, err := bind(&stmt, tx, args, statement.exists)
+	if err != nil {
+		return false, fail("bind", "", nil, err)
+	}
+
+	var exists bool
+	log(tx, query, args)
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&exists); err != nil {
+		return false, fail("exists", query, args, err)
+	}
+	return exists, nil
+}
+
+// tableRef returns the name used to reference the supplied table expression from other expressions of the query.
+// This is the alias of the table, if provided (e.g. "users AS u" or "users u"), otherwise the table name.
+func tableRef(table string) string {
+	fields := strings.Fields(table)
+	if len(fields) == 0 {
+		return table
+	}
+	return fields[len(fields)-1]
+}
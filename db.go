@@ -7,9 +7,22 @@ import (
 
 // Options identifies optional parameters that may be used when performing queries. Default struct values
 // signify default behaviour.
+//
+// When Strict is set, each selected column is given an alias and the columns of the result set are verified against
+// the query before any rows are scanned. This guards against results being scanned into the wrong fields.
+//
+// When Rewrite is set, it is called with the [Statement] of each query right before the SQL is produced, after
+// runtime modifiers are applied and absent optional conditions are removed. The function may modify the statement,
+// for example to add a condition restricting results to a tenant or to rename a table. Values are bound to the
+// positional placeholders of an added condition using its Args. Queries derived from the statement, such as those
+// performed by [Count] and [Exists], are derived from the rewritten statement. Rewrite is not applied to [Raw] and
+// [Scalar] queries.
 type Options struct {
-	Namer  Namer
-	Logger func(query string, args []any)
+	Namer       Namer
+	Logger      func(query string, args []any)
+	Placeholder Placeholder
+	Strict      bool
+	Rewrite     func(stmt *Statement)
 }
 
 // Name with returns the defined Namer option or nil.
@@ -20,6 +33,27 @@ func (o *Options) NameWith() Namer {
 	return o.Namer
 }
 
+// BindWith returns the defined Placeholder option or the default [Question] style.
+func (o *Options) BindWith() Placeholder {
+	if o == nil {
+		return Question
+	}
+	return o.Placeholder
+}
+
+// StrictWith returns true if the Strict option is set.
+func (o *Options) StrictWith() bool {
+	return o != nil && o.Strict
+}
+
+// RewriteWith returns the defined Rewrite option or nil.
+func (o *Options) RewriteWith() func(*Statement) {
+	if o == nil {
+		return nil
+	}
+	return o.Rewrite
+}
+
 // Log calls the [Options.Logger] function if defined in the [Options].
 // query functions.
 func (o *Options) Log(query string, args []any) {
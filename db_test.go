@@ -17,7 +17,7 @@ func TestOpen(t *testing.T) {
 
 	type users struct{ Name string }
 	_, err = query.One(context.Background(), db, query.Identity[users])
-	if err.Error() != "no such table: users" {
+	if err == nil || err.Error() != "query: no such table: users" {
 		t.Errorf("unexpected error; got: %v", err)
 	}
 }
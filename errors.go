@@ -0,0 +1,167 @@
+package query
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFound is returned by [One] and [Scalar] when the query does not return any results. It wraps [sql.ErrNoRows]
+// so that it may also be identified using errors.Is(err, sql.ErrNoRows).
+var ErrNotFound = fmt.Errorf("query: not found: %w", sql.ErrNoRows)
+
+// Errors wrapped by a *[StructError] to describe why a query struct cannot be used to build a query.
+var (
+	ErrNotStruct       = errors.New("query must be a struct")
+	ErrJoinTag         = errors.New("join requires a struct tag describing the join conditions")
+	ErrUnexported      = errors.New("field must be exported to be scanned")
+	ErrUnsupportedType = errors.New("field type cannot be scanned")
+	ErrDuplicateMarker = errors.New("marker is defined more than once")
+	ErrTableConflict   = errors.New("conflicting table markers")
+	ErrIdent           = errors.New("many relationship or pointer join requires an identity column")
+	ErrPlaceholder     = errors.New("invalid placeholders")
+)
+
+// Error is returned when an operation performed against the database fails. The Op identifies the failed operation
+// (e.g. "bind", "query" or "scan"), the SQL holds the rendered query, if it was rendered, and Args holds the number
+// of arguments sent with it. When a result fails to scan, Field holds the dot separated path of the struct field that
+// the column was to be scanned into. The Err holds the underlying error, such as the error returned by the driver.
+type Error struct {
+	Op    string
+	SQL   string
+	Args  int
+	Field string
+	Err   error
+}
+
+// Error returns the error message.
+func (e *Error) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("%s: %s: %v", e.Op, e.Field, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Op, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// fail returns an *[Error] for the operation performed using the supplied query and arguments.
+func fail(op, query string, args []any, err error) error {
+	return &Error{Op: op, SQL: query, Args: len(args), Err: err}
+}
+
+// scanIndex matches the index of the column reported by a [sql.Rows.Scan] error.
+var scanIndex = regexp.MustCompile(`column index (\d+)`)
+
+// scanField returns the field path of the column reported by the supplied [sql.Rows.Scan] error. The fields hold the
+// path of the field bound to each result column. The lookup is best-effort: database/sql does not expose the failing
+// column other than through the text of its error, so an empty path is returned when the error does not name a column
+// index, such as when the number of result columns does not match the bindings.
+func scanField(err error, fields []string) string {
+	match := scanIndex.FindStringSubmatch(err.Error())
+	if match == nil {
+		return ""
+	}
+	i, _ := strconv.Atoi(match[1])
+	if i >= len(fields) {
+		return ""
+	}
+	return fields[i]
+}
+
+// TransformError is returned when a fallible transform function fails. The Row holds the index of the result that
+// failed to transform and the Err holds the error returned by the transform function.
+type TransformError struct {
+	Row int
+	Err error
+}
+
+// Error returns the error message.
+func (e *TransformError) Error() string {
+	return fmt.Sprintf("transform: row %d: %v", e.Row, e.Err)
+}
+
+// Unwrap returns the error returned by the transform function.
+func (e *TransformError) Unwrap() error {
+	return e.Err
+}
+
+// StructError is returned when a query struct cannot be used to build a query. The Type identifies the query struct
+// and the Path holds the dot separated names of the struct field at fault, or is empty if the query struct itself is
+// at fault. The Err describes the problem and wraps one of the errors declared alongside [ErrNotStruct].
+type StructError struct {
+	Type reflect.Type
+	Path string
+	Err  error
+}
+
+// Error returns the error message.
+func (e *StructError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("query: %s: %v", e.Type, e.Err)
+	}
+	return fmt.Sprintf("query: %s.%s: %v", e.Type, e.Path, e.Err)
+}
+
+// Unwrap returns the error describing the problem.
+func (e *StructError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned by [Validate] and holds a *[StructError] for each problem found in a query struct.
+type ValidationError struct {
+	Errors []error
+}
+
+// Error returns the messages of the problems found, separated by newlines.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the problems found.
+func (e *ValidationError) Unwrap() []error {
+	return e.Errors
+}
+
+// nestError returns the supplied error with the *[StructError] it holds, if any, moved to the field of the supplied
+// type with the given name.
+func nestError(typ reflect.Type, name string, err error) error {
+	var serr *StructError
+	if !errors.As(err, &serr) {
+		return err
+	}
+	path := name
+	if serr.Path != "" {
+		path += "." + serr.Path
+	}
+	return &StructError{Type: typ, Path: path, Err: serr.Err}
+}
+
+// nestErrors returns the problems held by the supplied error, moved to the field of the supplied type with the given
+// name.
+func nestErrors(typ reflect.Type, name string, err error) []error {
+	nested := problems(err)
+	for i, err := range nested {
+		nested[i] = nestError(typ, name, err)
+	}
+	return nested
+}
+
+// problems returns the problems held by a *[ValidationError], or the supplied error if it is not a validation error.
+func problems(err error) []error {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return append([]error(nil), verr.Errors...)
+	}
+	return []error{err}
+}
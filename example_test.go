@@ -70,3 +70,42 @@ func ExampleOne() {
 	fmt.Println(count)
 	// Output: 2
 }
+
+func ExampleCount() {
+	db := openDB()
+	count, _ := query.Count[usersQuery](context.Background(), db)
+	fmt.Println(count)
+	// Output: 2
+}
+
+// userLocationsQuery represents the SQL query:
+//
+//	SELECT users.id FROM users
+//	  INNER JOIN locations ON users.id = locations.user_id
+//	  WHERE (users.id = $1)
+type userLocationsQuery struct {
+	query.Table      `q:"users"`
+	query.Conditions `q:"users.id = $1"`
+	ID               int
+	Locations        struct {
+		ID int
+	} `q:"users.id = locations.user_id"`
+}
+
+func ExampleExists() {
+	db := openDB()
+	exists, _ := query.Exists[userLocationsQuery](context.Background(), db, 1)
+	fmt.Println(exists)
+	// Output: true
+}
+
+func ExampleSQL() {
+	type users struct {
+		query.Conditions `q:"name LIKE ?"`
+
+		Name string
+	}
+	generated, _ := query.SQL[users](nil, "J%")
+	fmt.Println(generated.SQL, generated.Args, generated.Positional)
+	// Output: SELECT users.name FROM users WHERE (name LIKE ?) [J%] 1
+}
@@ -0,0 +1,55 @@
+package query
+
+// Generated holds the SQL query generated by [SQL] for a query struct.
+type Generated struct {
+	// SQL holds the query that would be sent to the database.
+	SQL string
+	// Args holds the arguments that would be sent with the query.
+	Args []any
+	// Positional holds the number of positional arguments expected by the query struct.
+	Positional int
+	// Named holds the names of the named parameters of the query struct, including those of [Optional] conditions,
+	// in the order that they are first referenced.
+	Named []string
+}
+
+// SQL returns the SQL query and arguments that would be sent to the database by [All] for the Source type when
+// called with the supplied arguments, without executing the query. The query is produced by the same steps as [All]:
+// runtime modifiers are applied, absent optional conditions are removed, the Rewrite function of the options is
+// called, named parameters are resolved using the placeholder style of the options and columns are aliased when the
+// Strict option is set. The number of positional arguments and the named parameters expected by the query struct are
+// also returned, so that the arguments required by the query can be learned without supplying them. This allows the
+// query to be inspected by tests and tooling. Nil options use the default behaviour. A *[StructError] is returned if
+// the Source type is malformed and an *[Error] is returned if the arguments cannot be bound. Example:
+//
+//	type users struct {
+//		query.Conditions `q:"name LIKE :prefix"`
+//
+//		Name string
+//	}
+//	// Query: SELECT users.name FROM users WHERE (name LIKE $1); Args: [J%]; Named: [prefix]
+//	generated, _ := query.SQL[users](&query.Options{Placeholder: query.Dollar}, sql.Named("prefix", "J%"))
+func SQL[Source any](options *Options, args ...any) (Generated, error) {
+	tx := DB{Options: options}
+	stmt, err := plan[Source](nameWith(tx))
+	if err != nil {
+		return Generated{}, err
+	}
+	if strictWith(tx) {
+		stmt.alias()
+	}
+	var generated Generated
+	seen := make(map[string]bool)
+	for _, p := range placeholders(stmt.SQL()) {
+		if p.name != "" && !seen[p.name] {
+			seen[p.name] = true
+			generated.Named = append(generated.Named, p.name)
+		}
+	}
+	generated.Positional = positionalCount(stmt.SQL())
+	generated.SQL, generated.Args, err = bind(&stmt, tx, args, nil)
+	if err != nil {
+		return generated, fail("bind", "", nil, err)
+	}
+	return generated, nil
+}
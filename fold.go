@@ -0,0 +1,32 @@
+package query
+
+import "context"
+
+// Fold combines the results of the query into an accumulator. The fold function is called with the accumulated value
+// and each result, in the order that the results are returned by the query, and returns the next accumulated value.
+// The init value is returned if the query does not return any results. Results are passed to the fold function as
+// they are scanned, so no slice of results is created unless the Source type contains a many relationship. If the
+// query fails part way through, the value accumulated from the results folded so far is returned with the error, as
+// an accumulator such as a map may already have been modified by the fold function. Example:
+//
+//	type users struct {
+//		Name string
+//	}
+//	// Query: SELECT users.name FROM users
+//	longest, _ := query.Fold(ctx, db, 0, func(n int, u users) int {
+//		if len(u.Name) > n {
+//			return len(u.Name)
+//		}
+//		return n
+//	})
+//
+// Like [Map], the fold function is called while the rows are being read and must not perform queries using a
+// [Transaction] restricted to a single connection, such as a [sql.Tx].
+func Fold[Source, Acc any](ctx context.Context, tx Transaction, init Acc, fold func(Acc, Source) Acc, args ...any) (Acc, error) {
+	acc := init
+	err := each(ctx, tx, args, true, func(i int, src Source) error {
+		acc = fold(acc, src)
+		return nil
+	})
+	return acc, err
+}
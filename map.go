@@ -0,0 +1,83 @@
+package query
+
+import (
+	"context"
+	"fmt"
+)
+
+// DuplicateKeyError is returned by [MapUnique] when more than one result produces the same key. The Row holds the
+// index of the result which repeated the key.
+type DuplicateKeyError struct {
+	Key any
+	Row int
+}
+
+// Error returns the error message.
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("query: duplicate key %v at row %d", e.Key, e.Row)
+}
+
+// Map is like [All] but returns the results in a map keyed by the value returned by the key function for each
+// transformed result. Results are added to the map as they are scanned, so no intermediate slice is created unless
+// the Source type contains a many relationship. A later result replaces an earlier result with the same key; use
+// [MapUnique] to detect duplicate keys. Example:
+//
+//	type users struct {
+//		ID   int
+//		Name string
+//	}
+//	// Query: SELECT users.id, users.name FROM users
+//	byID, _ := query.Map(ctx, db, func(u users) int { return u.ID }, query.Identity[users])
+//
+// As the transform function is called while the rows are being read, it must not perform queries using a
+// [Transaction] restricted to a single connection, such as a [sql.Tx].
+func Map[Source any, Key comparable, Destination any](ctx context.Context, tx Transaction, key func(Destination) Key, transform Transform[Source, Destination], args ...any) (map[Key]Destination, error) {
+	return keyed(ctx, tx, key, transform, false, args)
+}
+
+// MapUnique is like [Map] but returns a *[DuplicateKeyError] if more than one result produces the same key.
+func MapUnique[Source any, Key comparable, Destination any](ctx context.Context, tx Transaction, key func(Destination) Key, transform Transform[Source, Destination], args ...any) (map[Key]Destination, error) {
+	return keyed(ctx, tx, key, transform, true, args)
+}
+
+// GroupInto is like [Map] but collects the results sharing a key into a slice, in the order that they are returned by
+// the query. Example:
+//
+//	type users struct {
+//		AddressID int
+//		Name      string
+//	}
+//	// Query: SELECT users.address_id, users.name FROM users
+//	byAddress, _ := query.GroupInto(ctx, db, func(u users) int { return u.AddressID }, query.Identity[users])
+func GroupInto[Source any, Key comparable, Destination any](ctx context.Context, tx Transaction, key func(Destination) Key, transform Transform[Source, Destination], args ...any) (map[Key][]Destination, error) {
+	groups := make(map[Key][]Destination)
+	err := each(ctx, tx, args, true, func(i int, src Source) error {
+		dest := transform(src)
+		k := key(dest)
+		groups[k] = append(groups[k], dest)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// keyed returns the transformed results of the query keyed by the key function. An error is returned if unique is set
+// and more than one result produces the same key.
+func keyed[Source any, Key comparable, Destination any](ctx context.Context, tx Transaction, key func(Destination) Key, transform Transform[Source, Destination], unique bool, args []any) (map[Key]Destination, error) {
+	results := make(map[Key]Destination)
+	err := each(ctx, tx, args, true, func(i int, src Source) error {
+		dest := transform(src)
+		k := key(dest)
+		if _, ok := results[k]; ok && unique {
+			return &DuplicateKeyError{Key: k, Row: i}
+		}
+		results[k] = dest
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
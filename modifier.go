@@ -0,0 +1,146 @@
+package query
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Modifier modifies the properties of a query at runtime. Modifiers are passed to [All] and [One] alongside the query
+// arguments and are applied as if the query struct was embedded in a struct defining the same properties: conditions
+// and ordering are added after those defined by the query struct, while limits and offsets replace them. Use
+// [ReplaceOrder] to replace the ordering defined by the query struct. The modifiers are named Order, LimitTo and
+// OffsetBy, rather than after the [OrderBy], [Limit] and [Offset] markers, as those names are taken by the markers.
+// Example:
+//
+//	type users struct {
+//		query.OrderBy `q:"name"`
+//
+//		Name string
+//	}
+//	// Query: SELECT users.name FROM users WHERE (name LIKE ?) ORDER BY name, id DESC LIMIT 10
+//	results, _ := query.All(ctx, db, query.Identity[users],
+//		query.Where("name LIKE ?", "J%"), query.Order("id DESC"), query.LimitTo(10))
+type Modifier struct {
+	modify func(stmt *statement, n int) []any
+}
+
+// Where returns a [Modifier] which adds a condition to the query. The positional placeholders of the condition are
+// bound to the supplied arguments, independent of the arguments of the query. Named parameters are bound to the
+// named arguments of the query.
+func Where(expr string, args ...any) Modifier {
+	return Modifier{func(stmt *statement, n int) []any {
+		expr, named := bindExpr(expr, args, fmt.Sprintf("_where%d", n))
+		stmt.conditions = append(stmt.conditions, condition{expr: expr})
+		return named
+	}}
+}
+
+// bindExpr rewrites the positional placeholders of the expression to named parameters, prefixed by the supplied
+// prefix, and returns the expression along with the named arguments binding the parameters to the supplied
+// arguments. Named parameters of the expression are left unchanged.
+func bindExpr(expr string, args []any, prefix string) (string, []any) {
+	var (
+		w     strings.Builder
+		named []any
+		next  int
+		last  int
+	)
+	for _, p := range placeholders(expr) {
+		if p.name != "" {
+			continue
+		}
+		idx := next
+		if p.ordinal > 0 {
+			idx = p.ordinal - 1
+		} else {
+			next++
+		}
+		name := fmt.Sprintf("%s_%d", prefix, idx+1)
+		if idx < len(args) {
+			named = append(named, sql.Named(name, args[idx]))
+		}
+		w.WriteString(expr[last:p.start])
+		w.WriteByte(':')
+		w.WriteString(name)
+		last = p.end
+	}
+	w.WriteString(expr[last:])
+	return w.String(), named
+}
+
+// Order returns a [Modifier] which adds ordering to the query. The expression is added to the query as provided and
+// must not contain untrusted input.
+func Order(expr string) Modifier {
+	return Modifier{func(stmt *statement, n int) []any {
+		stmt.order = append(stmt.order, expr)
+		return nil
+	}}
+}
+
+// ReplaceOrder returns a [Modifier] which replaces the ordering of the query, including the ordering defined by
+// [OrderBy] markers of the query struct and its joins, so that the results are sorted by the expression alone. The
+// expression is added to the query as provided and must not contain untrusted input.
+func ReplaceOrder(expr string) Modifier {
+	return Modifier{func(stmt *statement, n int) []any {
+		stmt.eachStatement(func(s *statement) {
+			s.order = nil
+		})
+		stmt.order = []string{expr}
+		return nil
+	}}
+}
+
+// LimitTo returns a [Modifier] which limits the number of results returned by the query.
+func LimitTo(limit int) Modifier {
+	return Modifier{func(stmt *statement, n int) []any {
+		stmt.limit = strconv.Itoa(limit)
+		return nil
+	}}
+}
+
+// OffsetBy returns a [Modifier] which offsets the results returned by the query.
+func OffsetBy(offset int) Modifier {
+	return Modifier{func(stmt *statement, n int) []any {
+		stmt.offset = strconv.Itoa(offset)
+		return nil
+	}}
+}
+
+// hasModifier returns true if any of the supplied arguments is a [Modifier].
+func hasModifier(args []any) bool {
+	for _, arg := range args {
+		if _, ok := arg.(Modifier); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// modify applies the modifiers found in the supplied arguments to the statement. The remaining arguments are
+// returned along with the named arguments required by the modifiers.
+func modify(stmt *statement, args []any) []any {
+	var remaining []any
+	n := 0
+	for i, arg := range args {
+		m, ok := arg.(Modifier)
+		if !ok {
+			if remaining != nil {
+				remaining = append(remaining, arg)
+			}
+			continue
+		}
+		if remaining == nil {
+			remaining = append(make([]any, 0, len(args)), args[:i]...)
+		}
+		if m.modify != nil {
+			n++
+			remaining = append(remaining, m.modify(stmt, n)...)
+		}
+	}
+	if remaining == nil {
+		return args
+	}
+	return remaining
+}
@@ -0,0 +1,365 @@
+package query
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a cursor token cannot be decoded or its signature does not match.
+var ErrInvalidCursor = errors.New("query: invalid cursor")
+
+// Page identifies a page of results.
+type Page[T any] struct {
+	// Items holds the results of the page.
+	Items []T
+	// Next holds the cursor token used to request the following page. It is empty when there are no more results.
+	// Only pages returned by [Seek] provide a cursor token.
+	Next string
+	// Number holds the one-based page number. Only pages returned by [AllPage] are numbered.
+	Number int
+	// Size holds the maximum number of results in the page.
+	Size int
+	// Total holds the total number of results across all pages. Only pages returned by [AllPage] provide a total.
+	Total int
+	// Pages holds the total number of pages. Only pages returned by [AllPage] provide a page count.
+	Pages int
+}
+
+// Paging describes a page of results to be retrieved using offset pagination.
+type Paging struct {
+	// Number holds the one-based page number.
+	Number int
+	// Size holds the maximum number of results in the page.
+	Size int
+}
+
+// AllPage is like [All] but returns a single numbered page of results along with the total number of results. The
+// total is retrieved using a count query derived from the Source type which shares the joins and conditions of the
+// query, but not its columns, ordering, limit and offset. The limit and offset of the query are replaced by those of
+// the page. Queries containing a many relationship cannot be paginated. Example:
+//
+//	type users struct {
+//		query.Conditions `q:"name LIKE ?"`
+//		query.OrderBy    `q:"name"`
+//
+//		Name string
+//	}
+//	// Query: SELECT COUNT(*) FROM users WHERE (name LIKE ?)
+//	// Query: SELECT users.name FROM users WHERE (name LIKE ?) ORDER BY name LIMIT 20 OFFSET 40
+//	page, _ := query.AllPage(ctx, db, query.Identity[users], query.Paging{Number: 3, Size: 20}, "J%")
+func AllPage[Source, Destination any](ctx context.Context, tx Transaction, transform Transform[Source, Destination], paging Paging, args ...any) (Page[Destination], error) {
+	page := Page[Destination]{Number: paging.Number, Size: paging.Size}
+	if paging.Size <= 0 || paging.Number <= 0 {
+		return page, fmt.Errorf("page: page number and size must be positive")
+	}
+	var src Source
+	if hasMany(reflect.TypeOf(src)) {
+		return page, fmt.Errorf("page: %T contains a many relationship", src)
+	}
+
+	stmt, err := plan[Source](nameWith(tx))
+	if err != nil {
+		return page, err
+	}
+	query, countArgs, err := bind(&stmt, tx, args, func(stmt statement) statement {
+		return stmt.count("COUNT(*)")
+	})
+	if err != nil {
+		return page, fail("bind", "", nil, err)
+	}
+	log(tx, query, countArgs)
+	if err := tx.QueryRowContext(ctx, query, countArgs...).Scan(&page.Total); err != nil {
+		return page, fail("count", query, countArgs, err)
+	}
+	page.Pages = (page.Total + paging.Size - 1) / paging.Size
+
+	modifiers := append(make([]any, 0, len(args)+2), args...)
+	modifiers = append(modifiers, LimitTo(paging.Size), OffsetBy((paging.Number-1)*paging.Size))
+	page.Items, err = All(ctx, tx, transform, modifiers...)
+	return page, err
+}
+
+// Keyset describes a page of results to be retrieved using keyset pagination.
+type Keyset struct {
+	// Sort holds the comma separated keys that the results are ordered by, using the same format as [Sort]. The
+	// combination of keys must uniquely identify a row and the keys must not be null. The last key will usually be
+	// the primary key.
+	Sort string
+	// Size holds the maximum number of results in the page.
+	Size int
+	// After holds the cursor token of the previous page, or is empty to request the first page.
+	After string
+	// Secret holds the key used to sign cursor tokens so that tampered tokens are rejected. It must not be empty and
+	// should be a random value of at least 32 bytes which is kept private.
+	Secret []byte
+}
+
+// Seek returns a page of results ordered by the keys of the [Keyset]. Rather than skipping rows using an offset, the
+// query seeks past the key values of the last row of the previous page, which are encoded in the signed cursor token
+// returned by [Page.Next]. Example:
+//
+//	type users struct {
+//		ID   int
+//		Name string
+//	}
+//	// Query: SELECT users.id, users.name FROM users ORDER BY users.name, users.id LIMIT 21
+//	page, _ := query.Seek(ctx, db, query.Identity[users], query.Keyset{Sort: "name,id", Size: 20, Secret: secret})
+//	// Query: SELECT users.id, users.name FROM users WHERE ((users.name, users.id) > (?, ?))
+//	//   ORDER BY users.name, users.id LIMIT 21
+//	page, _ = query.Seek(ctx, db, query.Identity[users], query.Keyset{Sort: "name,id", Size: 20, After: page.Next, Secret: secret})
+//
+// Keys sorted in a mix of ascending and descending order are supported. Queries containing a many relationship
+// cannot be paginated using a keyset. An error is returned if the [Keyset] has no Secret or if a key value cannot be
+// encoded in a cursor token. [ErrInvalidCursor] is returned if the cursor token is invalid.
+func Seek[Source, Destination any](ctx context.Context, tx Transaction, transform Transform[Source, Destination], keyset Keyset, args ...any) (Page[Destination], error) {
+	page := Page[Destination]{Size: keyset.Size}
+	if keyset.Size <= 0 {
+		return page, fmt.Errorf("seek: page size must be positive")
+	}
+	if len(keyset.Secret) == 0 {
+		return page, fmt.Errorf("seek: a secret is required to sign cursor tokens")
+	}
+	var src Source
+	if hasMany(reflect.TypeOf(src)) {
+		return page, fmt.Errorf("seek: %T contains a many relationship", src)
+	}
+
+	stmt, err := plan[Source](nameWith(tx))
+	if err != nil {
+		return page, err
+	}
+	var (
+		keys   []seekKey
+		fields [][]int
+	)
+	for _, key := range parseSort(keyset.Sort) {
+		expr, field, ok := stmt.column(key.name)
+		if !ok {
+			return page, &SortError{Key: key.name}
+		}
+		if field == nil {
+			return page, fmt.Errorf("seek: key %q cannot be read from %T", key.name, src)
+		}
+		keys = append(keys, seekKey{name: key.name, expr: expr, desc: key.desc})
+		fields = append(fields, field)
+	}
+	if len(keys) == 0 {
+		return page, fmt.Errorf("seek: sort keys are required")
+	}
+
+	modifiers := append(make([]any, 0, len(args)+2), args...)
+	modifiers = append(modifiers, Modifier{func(stmt *statement, n int) []any {
+		order := make([]string, len(keys), len(keys)+len(stmt.order))
+		for i, key := range keys {
+			order[i] = key.expr
+			if key.desc {
+				order[i] += " DESC"
+			}
+		}
+		stmt.order = append(order, stmt.order...)
+		stmt.limit = strconv.Itoa(keyset.Size + 1)
+		return nil
+	}})
+	if keyset.After != "" {
+		values, err := decodeCursor(keyset.Secret, keyset.Sort, keyset.After)
+		if err != nil || len(values) != len(keys) {
+			return page, ErrInvalidCursor
+		}
+		modifiers = append(modifiers, seekAfter(keys, values))
+	}
+
+	var (
+		last   [][]any
+		keyErr error
+	)
+	results, err := AllErr(ctx, tx, func(src Source) (Destination, error) {
+		row := reflect.ValueOf(src)
+		values := make([]any, len(fields))
+		for i, field := range fields {
+			var err error
+			values[i], err = driver.DefaultParameterConverter.ConvertValue(row.FieldByIndex(field).Interface())
+			if err != nil {
+				var dest Destination
+				keyErr = fmt.Errorf("seek: key %q: %w", keys[i].name, err)
+				return dest, keyErr
+			}
+		}
+		last = append(last, values)
+		return transform(src), nil
+	}, modifiers...)
+	if keyErr != nil {
+		return page, keyErr
+	}
+	if err != nil {
+		return page, err
+	}
+
+	page.Items = results
+	if len(results) > keyset.Size {
+		page.Items = results[:keyset.Size]
+		page.Next, err = encodeCursor(keyset.Secret, keyset.Sort, last[keyset.Size-1])
+		if err != nil {
+			return page, fmt.Errorf("seek: %w", err)
+		}
+	}
+	return page, nil
+}
+
+// seekKey identifies a column expression used to order and seek a keyset page.
+type seekKey struct {
+	name string
+	expr string
+	desc bool
+}
+
+// seekAfter returns a [Modifier] which adds the condition used to seek past the supplied key values. When all keys
+// are sorted in the same direction a row value comparison is used, otherwise the comparison is expanded.
+func seekAfter(keys []seekKey, values []any) Modifier {
+	return Modifier{func(stmt *statement, n int) []any {
+		args := make([]any, len(values))
+		params := make([]string, len(values))
+		for i, value := range values {
+			name := fmt.Sprintf("_seek%d_%d", n, i+1)
+			args[i] = sql.Named(name, value)
+			params[i] = ":" + name
+		}
+
+		mixed := false
+		for _, key := range keys {
+			mixed = mixed || key.desc != keys[0].desc
+		}
+		if !mixed {
+			exprs := make([]string, len(keys))
+			for i, key := range keys {
+				exprs[i] = key.expr
+			}
+			op := " > "
+			if keys[0].desc {
+				op = " < "
+			}
+			expr := strings.Join(exprs, ", ") + op + strings.Join(params, ", ")
+			if len(keys) > 1 {
+				expr = "(" + strings.Join(exprs, ", ") + ")" + op + "(" + strings.Join(params, ", ") + ")"
+			}
+			stmt.conditions = append(stmt.conditions, condition{expr: expr})
+			return args
+		}
+
+		group := condition{operator: operatorOr}
+		for i, key := range keys {
+			branch := condition{operator: operatorAnd}
+			for j := 0; j < i; j++ {
+				branch.branches = append(branch.branches, condition{expr: keys[j].expr + " = " + params[j]})
+			}
+			op := " > "
+			if key.desc {
+				op = " < "
+			}
+			branch.branches = append(branch.branches, condition{expr: key.expr + op + params[i]})
+			group.branches = append(group.branches, branch)
+		}
+		stmt.conditions = append(stmt.conditions, group)
+		return args
+	}}
+}
+
+// cursorValue identifies a key value encoded in a cursor token. Only the field matching the type of the value is
+// set; a nil value sets no fields.
+type cursorValue struct {
+	Int    *int64     `json:"i,omitempty"`
+	Float  *float64   `json:"f,omitempty"`
+	Bool   *bool      `json:"b,omitempty"`
+	Bytes  []byte     `json:"y,omitempty"`
+	String *string    `json:"s,omitempty"`
+	Time   *time.Time `json:"t,omitempty"`
+}
+
+// encodeCursor returns a cursor token encoding the supplied driver values. The token is signed using the secret and
+// the sort keys so that it cannot be modified or used with a different ordering.
+func encodeCursor(secret []byte, sort string, values []any) (string, error) {
+	encoded := make([]cursorValue, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case nil:
+		case int64:
+			encoded[i].Int = &v
+		case float64:
+			encoded[i].Float = &v
+		case bool:
+			encoded[i].Bool = &v
+		case []byte:
+			encoded[i].Bytes = v
+		case string:
+			encoded[i].String = &v
+		case time.Time:
+			encoded[i].Time = &v
+		default:
+			return "", fmt.Errorf("unsupported cursor value of type %T", value)
+		}
+	}
+	payload, err := json.Marshal(encoded)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(signCursor(secret, sort, payload)), nil
+}
+
+// decodeCursor returns the driver values encoded in the supplied cursor token after verifying its signature.
+func decodeCursor(secret []byte, sort, token string) ([]any, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, signCursor(secret, sort, payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var encoded []cursorValue
+	if err := json.Unmarshal(payload, &encoded); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	values := make([]any, len(encoded))
+	for i, v := range encoded {
+		switch {
+		case v.Int != nil:
+			values[i] = *v.Int
+		case v.Float != nil:
+			values[i] = *v.Float
+		case v.Bool != nil:
+			values[i] = *v.Bool
+		case v.Bytes != nil:
+			values[i] = v.Bytes
+		case v.String != nil:
+			values[i] = *v.String
+		case v.Time != nil:
+			values[i] = *v.Time
+		}
+	}
+	return values, nil
+}
+
+// signCursor returns the signature of the cursor payload.
+func signCursor(secret []byte, sort string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(sort))
+	mac.Write([]byte{0})
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
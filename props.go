@@ -33,6 +33,15 @@ type OrderBy struct{}
 //	}
 type GroupBy struct{}
 
+// Having can be composed in a query struct to assign conditions to grouped results. This is the HAVING section of the
+// SQL statement. Example:
+//
+//	type users struct {
+//	  query.GroupBy `q:"name"`
+//	  query.Having  `q:"COUNT(*) > 1"`
+//	}
+type Having struct{}
+
 // Limit can be composed in a query struct to limit the number of results. This is the LIMIT section of the SQL statement.
 // Example:
 //
@@ -58,3 +67,48 @@ type Offset struct{}
 //		} `users.address_id = addresses.id`
 //	}
 type LeftJoin struct{}
+
+// AnyOf can be composed in a struct to define a condition group where at least one of the group's conditions must
+// match. The conditions of the group are joined using OR. Conditions are defined by [Conditions] fields, nested
+// condition groups, or embedded structs which contribute a branch made up of their own conditions. Example:
+//
+//	type users struct {
+//		Names struct {
+//			query.AnyOf
+//			Bob  query.Conditions `q:"name = 'Bob'"`
+//			John query.Conditions `q:"name = 'John'"`
+//		}
+//
+//		Name string
+//	}
+//	// Query: SELECT users.name FROM users WHERE ((name = 'Bob') OR (name = 'John'))
+type AnyOf struct{}
+
+// AllOf can be composed in a struct to define a condition group where all of the group's conditions must match. The
+// conditions of the group are joined using AND. It is most useful as a branch of an [AnyOf] group. Example:
+//
+//	type users struct {
+//		Filter struct {
+//			query.AnyOf
+//			Admin query.Conditions `q:"admin"`
+//			Owner struct {
+//				query.AllOf
+//				Active query.Conditions `q:"active"`
+//				Owner  query.Conditions `q:"owner_id = ?"`
+//			}
+//		}
+//	}
+type AllOf struct{}
+
+// Optional can be composed in a query struct to assign a query condition which is only applied when the named
+// arguments it references are provided. Named arguments are supplied using [sql.Named] and referenced with a colon
+// prefix. The condition is omitted, along with its arguments, when an argument is absent or nil. Example:
+//
+//	type users struct {
+//	  query.Optional `q:"name = :name"`
+//	}
+//	// Query: SELECT ... FROM users WHERE (name = ?)
+//	query.All(ctx, db, query.Identity[users], sql.Named("name", "Bob"))
+//	// Query: SELECT ... FROM users
+//	query.All(ctx, db, query.Identity[users])
+type Optional struct{}
@@ -4,10 +4,12 @@ package query
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // Transaction identifies a queryable database handle. This will most likely be a [sql.DB], [sql.Tx] or equivalent
@@ -22,6 +24,14 @@ type Transaction interface {
 // transformed output.
 type Transform[Source, Destination any] func(Source) Destination
 
+// TransformErr is like [Transform] but may fail. It is used by [AllErr] and [OneErr] for conversions which may not
+// succeed, such as decoding JSON or validating an enumeration.
+type TransformErr[Source, Destination any] func(Source) (Destination, error)
+
+// TransformContext is like [TransformErr] but also receives the context of the query. It is used by [AllContext] and
+// [OneContext].
+type TransformContext[Source, Destination any] func(context.Context, Source) (Destination, error)
+
 // The Identity function is a Transform function that returns the original value. This function can be used as the
 // transform when the caller wishes to receive the source value.
 func Identity[Source any](src Source) Source { return src }
@@ -81,72 +91,417 @@ func Identity[Source any](src Source) Source { return src }
 //		} `q:"users.address_id = addresses.id"`
 //	}
 //
-// The caller should note that the Source value is reused on each row iteration and should take care to ensure that
-// values are copied in the transform function. Slices excepted.
+// A join defined as a pointer to a struct is set to nil when the joined row is absent, such as with a [LeftJoin], so
+// its fields do not need to be nullable. Like "has many" joins, the primary key column of the joined table is
+// selected to detect the absent row. Other pointer fields, such as *string or *[time.Time], are scanned as nullable
+// columns. Example:
+//
+//	type users struct {
+//		Name      *string
+//		Addresses *struct {
+//			query.LeftJoin
+//
+//			City string
+//		} `q:"users.address_id = addresses.id"`
+//	}
+//
+// Properties may also be added to the query at runtime by passing [Modifier] values, such as [Where], [Order],
+// [LimitTo] and [OffsetBy], alongside the query arguments. Example:
 //
-// An error will be returned if any of the [Transaction] operations fail.
+//	results, _ := query.All(context.Background(), db, query.Identity[users], query.Where("name = ?", name))
+//
+// Each result is scanned into its own Source value, so values held by a result, such as the state of a [sql.Scanner]
+// field, are not shared with other results. Byte slice fields, including [sql.RawBytes], receive a copy of the bytes
+// returned by the driver.
+//
+// An error will be returned if any of the [Transaction] operations fail. A *[StructError] is returned, before the
+// query is sent, if the Source type cannot be used to build a query.
 func All[Source, Destination any](ctx context.Context, tx Transaction, transform Transform[Source, Destination], args ...any) ([]Destination, error) {
+	return AllContext(ctx, tx, func(_ context.Context, src Source) (Destination, error) {
+		return transform(src), nil
+	}, args...)
+}
+
+// AllErr is like [All] but uses a transform function which may fail. The query is aborted on the first failed
+// transform and a *[TransformError] identifying the result is returned.
+func AllErr[Source, Destination any](ctx context.Context, tx Transaction, transform TransformErr[Source, Destination], args ...any) ([]Destination, error) {
+	return AllContext(ctx, tx, func(_ context.Context, src Source) (Destination, error) {
+		return transform(src)
+	}, args...)
+}
+
+// AllContext is like [AllErr] but the transform function also receives the context of the query.
+func AllContext[Source, Destination any](ctx context.Context, tx Transaction, transform TransformContext[Source, Destination], args ...any) ([]Destination, error) {
+	var transformed []Destination
+	err := each(ctx, tx, args, false, func(i int, src Source) error {
+		dest, err := transform(ctx, src)
+		if err != nil {
+			return &TransformError{Row: i, Err: err}
+		}
+		transformed = append(transformed, dest)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if transformed == nil {
+		transformed = []Destination{}
+	}
+	return transformed, nil
+}
+
+// each queries the results of the Source type and calls the yield function with the index of each result and the
+// result. If stream is set, each result is yielded as soon as it is scanned and is not retained, unless the Source type
+// contains a many relationship which requires every row to be scanned to build up the value hierarchy. Otherwise, the
+// results are yielded once all of the rows are scanned. The iteration is aborted if the yield function returns an
+// error.
+func each[Source any](ctx context.Context, tx Transaction, args []any, stream bool, yield func(i int, src Source) error) error {
 	var results []Source
-	query, bindings, complete := prepareSet(nameWith(tx), &results)
+	stmt, complete, err := preparer{namer: nameWith(tx)}.prepareSet(&results)
+	if err != nil {
+		return err
+	}
+	strict := strictWith(tx)
+	if strict {
+		stmt.alias()
+	}
 
+	query, args, err := bind(&stmt, tx, args, nil)
+	if err != nil {
+		return fail("bind", "", nil, err)
+	}
+	bindings := stmt.bindings()
 	log(tx, query, args)
-	rows, err := tx.QueryContext(ctx, query.SQL(), args...)
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("query: %v", err)
+		return fail("query", query, args, err)
 	}
 	defer rows.Close()
 
-	for rows.Next() {
-		if err := rows.Scan(bindings...); err != nil {
-			return nil, fmt.Errorf("scan: %w", err)
+	if strict {
+		if err := verify(rows, &stmt, query, args); err != nil {
+			return err
 		}
-		complete()
 	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("close: %w", err)
+	stream = stream && !hasMany(reflect.TypeOf(results).Elem())
+	n := 0
+	err = scan(rows, query, args, bindings, stmt.fields(), func() error {
+		complete()
+		if !stream {
+			return nil
+		}
+		src := results[0]
+		results = results[:0]
+		n++
+		return yield(n-1, src)
+	})
+	if err != nil {
+		return err
 	}
 
-	transformed := make([]Destination, len(results))
-	for i, result := range results {
-		transformed[i] = transform(result)
+	for i, src := range results {
+		if err := yield(n+i, src); err != nil {
+			return err
+		}
 	}
-	return transformed, nil
+	return nil
 }
 
-// One is like [All] but returns only the first result of the query. An error will be returned if any of
-// the [Transaction] operations fail.
+// One is like [All] but returns only the first result of the query. [ErrNotFound] is returned if the query does not
+// return any results. An error will be returned if any of the [Transaction] operations fail. The transform function is
+// not called when an error is returned.
 func One[Source, Destination any](ctx context.Context, tx Transaction, transform Transform[Source, Destination], args ...any) (Destination, error) {
+	return OneContext(ctx, tx, func(_ context.Context, src Source) (Destination, error) {
+		return transform(src), nil
+	}, args...)
+}
+
+// OneErr is like [One] but uses a transform function which may fail. A *[TransformError] is returned if the transform
+// fails.
+func OneErr[Source, Destination any](ctx context.Context, tx Transaction, transform TransformErr[Source, Destination], args ...any) (Destination, error) {
+	return OneContext(ctx, tx, func(_ context.Context, src Source) (Destination, error) {
+		return transform(src)
+	}, args...)
+}
+
+// OneContext is like [OneErr] but the transform function also receives the context of the query.
+func OneContext[Source, Destination any](ctx context.Context, tx Transaction, transform TransformContext[Source, Destination], args ...any) (Destination, error) {
 	var src Source
 
-	// When the query contains a many relationship the call is passed to the [All] function to evaluate all of the
-	// incoming rows to build up the necessary value hierarchy. The first result returned by [All] is passed
-	// back to the caller.
+	// When the query contains a many relationship all of the incoming rows are evaluated to build up the necessary
+	// value hierarchy. Only the first result is transformed and passed back to the caller.
 	if hasMany(reflect.TypeOf(src)) {
-		var dest Destination
-		results, err := All(ctx, tx, transform, args...)
-		if err != nil {
+		found := false
+		err := each(ctx, tx, args, false, func(i int, result Source) error {
+			if i == 0 {
+				src, found = result, true
+			}
+			return nil
+		})
+		switch {
+		case err != nil:
+			var dest Destination
 			return dest, err
+		case !found:
+			var dest Destination
+			return dest, ErrNotFound
 		}
-		if len(results) == 0 {
-			return dest, sql.ErrNoRows
+		dest, err := transform(ctx, src)
+		if err != nil {
+			var zero Destination
+			return zero, &TransformError{Row: 0, Err: err}
 		}
-		return results[0], nil
+		return dest, nil
 	}
 
-	query, bindings, _ := prepare(nameWith(tx), reflect.ValueOf(&src), 0)
+	stmt, complete, err := preparer{namer: nameWith(tx)}.prepare(reflect.ValueOf(&src), 0)
+	if err != nil {
+		var dest Destination
+		return dest, err
+	}
+	strict := strictWith(tx)
+	if strict {
+		stmt.alias()
+	}
+	query, args, err := bind(&stmt, tx, args, nil)
+	if err != nil {
+		var dest Destination
+		return dest, fail("bind", "", nil, err)
+	}
 	log(tx, query, args)
-	err := tx.QueryRowContext(ctx, query.SQL(), args...).Scan(bindings...)
-	return transform(src), err
+	if strict {
+		err = scanFirst(ctx, tx, &stmt, query, args)
+	} else {
+		err = scanRow(tx.QueryRowContext(ctx, query, args...), &stmt, query, args)
+	}
+	if err != nil {
+		var dest Destination
+		return dest, err
+	}
+	complete(nil, reflect.ValueOf(&src).Elem())
+	dest, err := transform(ctx, src)
+	if err != nil {
+		var zero Destination
+		return zero, &TransformError{Row: 0, Err: err}
+	}
+	return dest, nil
+}
+
+// Maybe is like [One] but reports whether the query returned a result rather than returning [ErrNotFound]. Example:
+//
+//	user, ok, err := query.Maybe(ctx, db, query.Identity[users], userID)
+func Maybe[Source, Destination any](ctx context.Context, tx Transaction, transform Transform[Source, Destination], args ...any) (Destination, bool, error) {
+	dest, err := One(ctx, tx, transform, args...)
+	if errors.Is(err, ErrNotFound) {
+		return dest, false, nil
+	}
+	return dest, err == nil, err
+}
+
+// AllNamed is like [All] but binds the named parameters of the query to the supplied params. Named parameters are
+// referenced in struct tags using a colon prefix. The params may be a map with string keys or a struct. Struct fields
+// are named using the "q" struct tag if provided, otherwise the name is inferred from the field name using the
+// [Namer]. Example:
+//
+//	type users struct {
+//		query.Conditions `q:"users.id = :user_id"`
+//		query.Limit      `q:":limit"`
+//
+//		Name string
+//	}
+//	type params struct {
+//		UserID int
+//		Limit  int
+//	}
+//	// Query: SELECT users.name FROM users WHERE (users.id = ?) LIMIT ?
+//	results, _ := query.AllNamed(ctx, db, query.Identity[users], params{UserID: 1, Limit: 10})
+//
+// Named parameters are resolved to the ordinal placeholders of the [Options.Placeholder] style. If the query also
+// contains positional placeholders, the style of the positional placeholders is used instead.
+func AllNamed[Source, Destination any](ctx context.Context, tx Transaction, transform Transform[Source, Destination], params any) ([]Destination, error) {
+	args, err := paramArgs(nameWith(tx), params)
+	if err != nil {
+		return nil, fail("bind", "", nil, err)
+	}
+	return All(ctx, tx, transform, args...)
+}
+
+// OneNamed is like [AllNamed] but returns only the first result of the query.
+func OneNamed[Source, Destination any](ctx context.Context, tx Transaction, transform Transform[Source, Destination], params any) (Destination, error) {
+	args, err := paramArgs(nameWith(tx), params)
+	if err != nil {
+		var dest Destination
+		return dest, fail("bind", "", nil, err)
+	}
+	return One(ctx, tx, transform, args...)
+}
+
+// AllWith is like [AllNamed] but binds the named parameters of the query to the fields of the typed Params struct.
+// The parameters are checked against the query before it is executed. An error is returned if the query references a
+// parameter that is not defined by Params, if a field of Params is not referenced by the query, or if the query
+// contains positional placeholders. Example:
+//
+//	type users struct {
+//		query.Conditions `q:"users.id = :user_id"`
+//
+//		Name string
+//	}
+//	type params struct {
+//		UserID int
+//	}
+//	results, _ := query.AllWith(ctx, db, query.Identity[users], params{UserID: 1})
+func AllWith[Source, Params, Destination any](ctx context.Context, tx Transaction, transform Transform[Source, Destination], params Params) ([]Destination, error) {
+	args, err := typedArgs[Source](nameWith(tx), params)
+	if err != nil {
+		return nil, fail("bind", "", nil, err)
+	}
+	return All(ctx, tx, transform, args...)
+}
+
+// OneWith is like [AllWith] but returns only the first result of the query.
+func OneWith[Source, Params, Destination any](ctx context.Context, tx Transaction, transform Transform[Source, Destination], params Params) (Destination, error) {
+	args, err := typedArgs[Source](nameWith(tx), params)
+	if err != nil {
+		var dest Destination
+		return dest, fail("bind", "", nil, err)
+	}
+	return One(ctx, tx, transform, args...)
+}
+
+// paramCheck identifies the result of checking a Params type against the query prepared for a Source type.
+type paramCheck struct {
+	source, params reflect.Type
+	namer          Namer
+}
+
+// paramChecks caches the result of checking the parameters of a query, keyed by paramCheck. The check only depends on
+// the types involved, so it is performed once for each combination rather than preparing the query on every call.
+var paramChecks sync.Map
+
+// typedArgs returns the named arguments described by the fields of the params struct after checking that they
+// match the named parameters of the query prepared for the Source type.
+func typedArgs[Source, Params any](namer Namer, params Params) ([]any, error) {
+	val := reflect.ValueOf(&params).Elem()
+	for val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("parameters of type %T must be a struct", params)
+	}
+
+	args := structArgs(namer, val)
+	key := paramCheck{source: reflect.TypeOf((*Source)(nil)).Elem(), params: val.Type(), namer: namer}
+	cacheable := reflect.TypeOf(namer).Comparable()
+	if cacheable {
+		if err, ok := paramChecks.Load(key); ok {
+			if err != nil {
+				return nil, err.(error)
+			}
+			return args, nil
+		}
+	}
+
+	stmt, err := plan[Source](namer)
+	if err == nil {
+		err = checkParams(stmt, args)
+	}
+	if cacheable {
+		paramChecks.Store(key, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// plan returns the statement prepared for the Source type without binding it to a value.
+func plan[Source any](namer Namer) (statement, error) {
+	var results []Source
+	stmt, _, err := preparer{namer: namer}.prepareSet(&results)
+	return stmt, err
+}
+
+// scan scans each of the rows of the query into the bindings, calling the completion function after each row is
+// scanned. The fields hold the path of the field of each binding, which is reported by the *[Error] returned when a
+// row fails to scan. Scanning is aborted if the completion function returns an error.
+func scan(rows *sql.Rows, query string, args []any, bindings []any, fields []string, complete func() error) error {
+	for rows.Next() {
+		if err := rows.Scan(bindings...); err != nil {
+			return &Error{Op: "scan", SQL: query, Args: len(args), Field: scanField(err, fields), Err: err}
+		}
+		if err := complete(); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fail("close", query, args, err)
+	}
+	return nil
+}
+
+// verify returns an error if the result columns of the rows of the query do not match the columns of the statement.
+func verify(rows *sql.Rows, stmt *statement, query string, args []any) error {
+	columns, err := rows.Columns()
+	if err == nil {
+		err = stmt.verify(columns)
+	}
+	if err != nil {
+		return fail("columns", query, args, err)
+	}
+	return nil
+}
+
+// scanFirst scans the first row of the query into the statement bindings after verifying the result columns.
+// [ErrNotFound] is returned if the query does not return any rows.
+func scanFirst(ctx context.Context, tx Transaction, stmt *statement, query string, args []any) error {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fail("query", query, args, err)
+	}
+	defer rows.Close()
+
+	if err := verify(rows, stmt, query, args); err != nil {
+		return err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return fail("close", query, args, err)
+		}
+		return ErrNotFound
+	}
+	if err := rows.Scan(stmt.bindings()...); err != nil {
+		return &Error{Op: "scan", SQL: query, Args: len(args), Field: scanField(err, stmt.fields()), Err: err}
+	}
+	if err := rows.Close(); err != nil {
+		return fail("close", query, args, err)
+	}
+	return nil
+}
+
+// scanRow scans the row of the query into the statement bindings. [ErrNotFound] is returned if the query did not
+// return a row.
+func scanRow(row *sql.Row, stmt *statement, query string, args []any) error {
+	if err := row.Err(); err != nil {
+		return fail("query", query, args, err)
+	}
+	err := row.Scan(stmt.bindings()...)
+	switch {
+	case err == sql.ErrNoRows:
+		return ErrNotFound
+	case err != nil:
+		return &Error{Op: "scan", SQL: query, Args: len(args), Field: scanField(err, stmt.fields()), Err: err}
+	}
+	return nil
 }
 
 // log calls the Log method on the [Transaction], if implemented, with the query and arguments used in the
 // calling query operation. This method is provided when a database is opened using [Open].
-func log(tx Transaction, query statement, args []any) {
+func log(tx Transaction, query string, args []any) {
 	txl, ok := tx.(interface{ Log(string, []any) })
 	if !ok {
 		return
 	}
-	txl.Log(query.SQL(), args)
+	txl.Log(query, args)
 }
 
 // nameWith returns the namer associated with the [Transaction], if implemented, and otherwise returns the default
@@ -164,43 +519,94 @@ func nameWith(tx Transaction) Namer {
 	return namer
 }
 
+// bindWith returns the placeholder style associated with the [Transaction], if implemented, and otherwise returns
+// the default [Question] style.
+func bindWith(tx Transaction) Placeholder {
+	txb, ok := tx.(interface{ BindWith() Placeholder })
+	if !ok {
+		return Question
+	}
+	return txb.BindWith()
+}
+
+// rewriteWith returns the statement rewrite function associated with the [Transaction], if implemented.
+func rewriteWith(tx Transaction) func(*Statement) {
+	txr, ok := tx.(interface{ RewriteWith() func(*Statement) })
+	if !ok {
+		return nil
+	}
+	return txr.RewriteWith()
+}
+
+// strictWith returns true if the [Transaction] requests strict column verification, if implemented.
+func strictWith(tx Transaction) bool {
+	txs, ok := tx.(interface{ StrictWith() bool })
+	return ok && txs.StrictWith()
+}
+
+// preparer prepares query structs using the namer. When validate is set, the checks performed by [Validate] are
+// also applied and every problem found is reported by a *[ValidationError], rather than returning the first.
+type preparer struct {
+	namer    Namer
+	validate bool
+}
+
+// report returns the first of the supplied problems, or records them and returns nil when validating so that the
+// query struct continues to be checked.
+func (p preparer) report(errs *[]error, problems ...error) error {
+	if !p.validate {
+		return problems[0]
+	}
+	*errs = append(*errs, problems...)
+	return nil
+}
+
 // prepareSet wraps prepareNestedSet to add the values to the top level slice rather than slices nested within
 // stored values. This is intended to be the top level call when preparing a set of results.
-func prepareSet(namer Namer, set any) (statement, []any, func()) {
+func (p preparer) prepareSet(set any) (statement, func(), error) {
 	val := reflect.ValueOf(set)
 	elem := val.Elem()
 	if !hasMany(elem.Type().Elem()) {
 		row := reflect.New(elem.Type().Elem())
-		stmt, bindings, _ := prepare(namer, row, 0)
-		return stmt, bindings, func() {
+		stmt, complete, err := p.prepare(row, 0)
+		return stmt, func() {
+			complete(nil, row.Elem())
 			elem.Set(reflect.Append(elem, row.Elem()))
-		}
+			row.Elem().Set(reflect.Zero(row.Elem().Type()))
+		}, err
 	}
 
-	stmt, bindings, complete := prepareNestedSet(namer, val)
-	return stmt, bindings, func() {
-		complete(nil, elem)
+	stmt, complete, err := p.prepareNestedSet(val)
+	if err != nil {
+		return stmt, nil, err
 	}
+	return stmt, func() {
+		complete(nil, elem)
+	}, nil
 }
 
-// prepareNestedSet returns a prepared SQL statement, bindings suitable for use by [sql.Rows.Scan], and a completion
-// function which is to be called after [sql.Rows.Scan] has been scanned into the bindings. The completion function
-// adds the bound results to the passed in slice value.
-func prepareNestedSet(namer Namer, set reflect.Value) (statement, []any, func(*rowRef, reflect.Value)) {
+// prepareNestedSet returns a prepared SQL statement, with columns bound to destinations suitable for use by
+// [sql.Rows.Scan], and a completion function which is to be called after [sql.Rows.Scan] has been scanned into the
+// bindings. The completion function adds the bound results to the passed in slice value. The row scanned into is
+// reset after each completion so that values held by a result are not reused by the following rows.
+func (p preparer) prepareNestedSet(set reflect.Value) (statement, func(*rowRef, reflect.Value), error) {
 	val := set.Elem()
 	row := reflect.New(val.Type().Elem())
-	stmt, bindings, complete := prepare(namer, row, 0)
+	stmt, complete, err := p.prepareIdent(row, val.Type(), 0)
+	if err != nil {
+		return stmt, nil, err
+	}
 
 	var ident any
 	stmt.columns = append([]column{{
-		name:     namer.Ident(val.Type()),
+		name:     p.namer.Ident(val.Type()),
 		useTable: true,
+		dest:     &ident,
 	}}, stmt.columns...)
-	bindings = append([]any{&ident}, bindings...)
 
 	visited := make(map[string]reflect.Value)
-	return stmt, bindings, func(parent *rowRef, val reflect.Value) {
-		_ = set
+	return stmt, func(parent *rowRef, val reflect.Value) {
+		defer row.Elem().Set(reflect.Zero(row.Elem().Type()))
 		if ident == nil {
 			return
 		}
@@ -215,84 +621,198 @@ func prepareNestedSet(namer Namer, set reflect.Value) (statement, []any, func(*r
 		added := val.Index(val.Len() - 1)
 		visited[hash] = added
 		complete(&ref, added)
+	}, nil
+}
+
+// prepareIdent prepares the row of a many relationship or pointer join, identified by the supplied type, whose
+// identity column is selected. When validating, the identity column is required.
+func (p preparer) prepareIdent(row reflect.Value, typ reflect.Type, depth int) (statement, func(*rowRef, reflect.Value), error) {
+	var errs []error
+	if p.validate && p.namer.Ident(typ) == "" {
+		errs = append(errs, &StructError{Type: row.Elem().Type(), Err: ErrIdent})
+	}
+	stmt, complete, err := p.prepare(row, depth)
+	if err != nil {
+		if !p.validate {
+			return stmt, nil, err
+		}
+		errs = append(errs, problems(err)...)
 	}
+	if len(errs) > 0 {
+		return stmt, nil, &ValidationError{Errors: errs}
+	}
+	return stmt, complete, nil
 }
 
-// prepare returns the prepared SQL query and destination bindings suitable for use by [sql.Rows.Scan].
-func prepare(namer Namer, src reflect.Value, depth int) (statement, []any, func(*rowRef, reflect.Value)) {
+// prepare returns the prepared SQL query with columns bound to destinations suitable for use by [sql.Rows.Scan]. A
+// *[StructError] is returned if the query struct is malformed, or a *[ValidationError] holding every problem found
+// when validating.
+func (p preparer) prepare(src reflect.Value, depth int) (statement, func(*rowRef, reflect.Value), error) {
 	val := src.Elem()
 	typ := val.Type()
-	bindings := make([]any, 0, typ.NumField())
+	if typ.Kind() != reflect.Struct {
+		return statement{}, nil, &StructError{Type: typ, Err: ErrNotStruct}
+	}
 	stmt := statement{
-		columns: make([]column, 0, cap(bindings)),
+		columns: make([]column, 0, typ.NumField()),
 	}
+	var (
+		errs      []error
+		seen      map[reflect.Type]bool
+		tablePath string
+	)
 	completion := func(*rowRef, reflect.Value) {}
 	for i := 0; i < typ.NumField(); i++ {
 		fld := typ.Field(i)
 		tag := fld.Tag.Get("q")
+		if p.validate && singleMarkers[fld.Type] {
+			if seen[fld.Type] {
+				errs = append(errs, &StructError{Type: typ, Path: fld.Name,
+					Err: fmt.Errorf("%w: %s", ErrDuplicateMarker, fld.Type)})
+			}
+			if seen == nil {
+				seen = make(map[reflect.Type]bool)
+			}
+			seen[fld.Type] = true
+		}
 		switch {
 		case fld.Type == reflect.TypeOf(Table{}):
+			if p.validate && tag != "" && stmt.table != "" && stmt.table != tag {
+				errs = append(errs, &StructError{Type: typ, Path: fld.Name,
+					Err: fmt.Errorf("%w: %q defined by %s", ErrTableConflict, stmt.table, pathName(tablePath))})
+			}
+			if stmt.table == "" {
+				tablePath = fld.Name
+			}
 			stmt.table = tag
 		case fld.Type == reflect.TypeOf(Conditions{}):
-			stmt.conditions = append(stmt.conditions, tag)
+			stmt.conditions = append(stmt.conditions, condition{expr: tag})
+		case fld.Type == reflect.TypeOf(Optional{}):
+			stmt.conditions = append(stmt.conditions, condition{expr: tag, optional: true})
+		case isGroup(fld.Type):
+			stmt.conditions = append(stmt.conditions, prepareGroup(fld.Type))
 		case fld.Type == reflect.TypeOf(OrderBy{}):
 			stmt.order = append(stmt.order, tag)
 		case fld.Type == reflect.TypeOf(GroupBy{}):
 			stmt.group = append(stmt.group, tag)
+		case fld.Type == reflect.TypeOf(Having{}):
+			stmt.having = append(stmt.having, condition{expr: tag})
 		case fld.Type == reflect.TypeOf(LeftJoin{}):
 			stmt.join = joinLeft
 		case fld.Type == reflect.TypeOf(Limit{}):
 			stmt.limit = tag
 		case fld.Type == reflect.TypeOf(Offset{}):
 			stmt.offset = tag
-		case fld.Type.Kind() == reflect.Slice:
-			if tag == "" {
-				panic(fmt.Errorf("%T.%s requires a struct tag describing the join conditions", src, fld.Name))
+		case fld.Type.Kind() == reflect.Slice && !isBytes(fld.Type):
+			if err := checkField(fld, tag); err != nil {
+				if err := p.report(&errs, &StructError{Type: typ, Path: fld.Name, Err: err}); err != nil {
+					return stmt, nil, err
+				}
+				continue
+			}
+			s, f, err := p.prepareNestedSet(val.Field(i).Addr())
+			if err != nil {
+				if err := p.report(&errs, nestErrors(typ, fld.Name, err)...); err != nil {
+					return stmt, nil, err
+				}
+				continue
 			}
-			s, b, f := prepareNestedSet(namer, val.Field(i).Addr())
+			s.index(-1, fld.Name)
 			if s.table == "" {
-				s.table = namer.Table(fieldInfo{fld})
+				s.table = p.namer.Table(fieldInfo{fld})
 			}
 			if s.join == joinNone {
 				s.join = joinInner
 			}
 			s.on = tag
 			stmt.joins = append(stmt.joins, s)
-			bindings = append(bindings, b...)
 			idx := i
 			completion = appendFn(completion, func(r *rowRef, v reflect.Value) {
 				f(r, v.Field(idx))
 			})
-		case fld.Type.Name() == "":
-			if tag == "" {
-				panic(fmt.Errorf("%T.%s requires a struct tag describing the join conditions", src, fld.Name))
+		case isPointerJoin(fld.Type):
+			if err := checkField(fld, tag); err != nil {
+				if err := p.report(&errs, &StructError{Type: typ, Path: fld.Name, Err: err}); err != nil {
+					return stmt, nil, err
+				}
+				continue
 			}
-			s, b, f := prepare(namer, val.Field(i).Addr(), depth+1)
+			s, f, err := p.preparePointer(fld.Type, depth+1)
+			if err != nil {
+				if err := p.report(&errs, nestErrors(typ, fld.Name, err)...); err != nil {
+					return stmt, nil, err
+				}
+				continue
+			}
+			s.index(-1, fld.Name)
 			if s.table == "" {
-				s.table = namer.Table(fieldInfo{fld})
+				s.table = p.namer.Table(fieldInfo{fld})
+			}
+			if s.join == joinNone {
+				s.join = joinInner
+			}
+			s.on = tag
+			stmt.joins = append(stmt.joins, s)
+			idx := i
+			completion = appendFn(completion, func(r *rowRef, v reflect.Value) {
+				f(r, v.Field(idx))
+			})
+		case fld.Type.Name() == "" && fld.Type.Kind() != reflect.Ptr && !isBytes(fld.Type):
+			if err := checkField(fld, tag); err != nil {
+				if err := p.report(&errs, &StructError{Type: typ, Path: fld.Name, Err: err}); err != nil {
+					return stmt, nil, err
+				}
+				continue
+			}
+			s, f, err := p.prepare(val.Field(i).Addr(), depth+1)
+			if err != nil {
+				if err := p.report(&errs, nestErrors(typ, fld.Name, err)...); err != nil {
+					return stmt, nil, err
+				}
+				continue
+			}
+			s.index(i, fld.Name)
+			if s.table == "" {
+				s.table = p.namer.Table(fieldInfo{fld})
 			}
 			if s.join == joinNone {
 				s.join = joinInner
 			}
 			s.on = tag
 			stmt.joins = append(stmt.joins, s)
-			bindings = append(bindings, b...)
 			idx := i
 			completion = appendFn(completion, func(r *rowRef, v reflect.Value) {
 				f(r, v.Field(idx))
 			})
 		default:
 			if fld.Anonymous {
-				s, b, f := prepare(namer, val.Field(i).Addr(), depth+1)
-				if stmt.table == "" {
-					stmt.table = s.table
+				s, f, err := p.prepare(val.Field(i).Addr(), depth+1)
+				if err != nil {
+					if err := p.report(&errs, nestErrors(typ, fld.Name, err)...); err != nil {
+						return stmt, nil, err
+					}
+					continue
+				}
+				s.index(i, fld.Name)
+				switch {
+				case stmt.table == "":
+					stmt.table, tablePath = s.table, fld.Name
+				case p.validate && s.table != "" && s.table != stmt.table:
+					errs = append(errs, &StructError{Type: typ, Path: fld.Name,
+						Err: fmt.Errorf("%w: %q defined by %s", ErrTableConflict, stmt.table, pathName(tablePath))})
 				}
 				stmt.columns = append(s.columns, stmt.columns...)
 				stmt.conditions = append(s.conditions, stmt.conditions...)
 				stmt.group = append(s.group, stmt.group...)
+				stmt.having = append(s.having, stmt.having...)
 				stmt.order = append(s.order, stmt.order...)
 				stmt.joins = append(s.joins, stmt.joins...)
-				bindings = append(bindings, b...)
+				if stmt.limit == "" {
+					stmt.limit = s.limit
+				}
+				if stmt.offset == "" {
+					stmt.offset = s.offset
+				}
 				idx := i
 				completion = appendFn(completion, func(r *rowRef, v reflect.Value) {
 					f(r, v.Field(idx))
@@ -300,32 +820,152 @@ func prepare(namer Namer, src reflect.Value, depth int) (statement, []any, func(
 				continue
 			}
 
-			col := column{name: tag}
-			if tag == "" {
-				col = column{
-					name:     namer.Column(fieldInfo{fld}),
-					useTable: true,
+			if !fld.IsExported() {
+				if err := p.report(&errs, &StructError{Type: typ, Path: fld.Name, Err: ErrUnexported}); err != nil {
+					return stmt, nil, err
 				}
+				continue
+			}
+			if p.validate && !scannable(fld.Type) {
+				errs = append(errs, &StructError{Type: typ, Path: fld.Name,
+					Err: fmt.Errorf("%w: %s", ErrUnsupportedType, fld.Type)})
+				continue
+			}
+			col := column{name: tag, field: []int{i}, path: fld.Name, dest: scanDest(val.Field(i).Addr())}
+			if tag == "" {
+				col.name = p.namer.Column(fieldInfo{fld})
+				col.useTable = true
 			}
 			stmt.columns = append(stmt.columns, col)
-			bindings = append(bindings, val.Field(i).Addr().Interface())
 		}
 	}
 
 	if depth == 0 && stmt.table == "" {
-		stmt.table = namer.Table(typ)
+		stmt.table = p.namer.Table(typ)
+	}
+	if len(errs) > 0 {
+		return stmt, nil, &ValidationError{Errors: errs}
+	}
+
+	return stmt, completion, nil
+}
+
+// isPointerJoin returns true if the input type is a pointer to a struct which describes a joined table. Pointers to
+// struct types which can be scanned, such as [time.Time] or [sql.Scanner] implementations, are columns.
+func isPointerJoin(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Struct && !scannable(typ.Elem())
+}
+
+// preparePointer returns a prepared SQL statement for the joined table described by the supplied pointer to struct
+// type, and a completion function which assigns the scanned struct to the passed in pointer value. Each column is
+// scanned into a nullable intermediate value so that the columns of an absent row may be null. Like a many
+// relationship, the identity column of the joined table is selected; the pointer is left nil when it is null.
+func (p preparer) preparePointer(typ reflect.Type, depth int) (statement, func(*rowRef, reflect.Value), error) {
+	row := reflect.New(typ.Elem())
+	stmt, complete, err := p.prepareIdent(row, typ, depth)
+	if err != nil {
+		return stmt, nil, err
+	}
+
+	type nullable struct{ dest, value reflect.Value }
+	var values []nullable
+	stmt.eachColumn(func(col *column, table string) {
+		dest := reflect.ValueOf(col.dest)
+		value := reflect.New(dest.Type())
+		values = append(values, nullable{dest: dest, value: value})
+		col.dest = value.Interface()
+	})
+
+	var ident any
+	stmt.columns = append([]column{{
+		name:     p.namer.Ident(typ),
+		useTable: true,
+		dest:     &ident,
+	}}, stmt.columns...)
+
+	return stmt, func(r *rowRef, v reflect.Value) {
+		if ident == nil {
+			return
+		}
+		defer row.Elem().Set(reflect.Zero(row.Elem().Type()))
+		for _, n := range values {
+			if n.value.Elem().IsNil() {
+				n.dest.Elem().Set(reflect.Zero(n.dest.Elem().Type()))
+			} else {
+				n.dest.Elem().Set(n.value.Elem().Elem())
+			}
+		}
+		// A row of a many relationship which has already been visited holds the joined struct of an earlier row,
+		// which may have collected results of its own.
+		if v.IsNil() {
+			v.Set(reflect.New(typ.Elem()))
+			v.Elem().Set(row.Elem())
+		}
+		complete(r, v.Elem())
+	}, nil
+}
+
+// checkField returns an error if the supplied struct field cannot be used to join a table.
+func checkField(fld reflect.StructField, tag string) error {
+	switch {
+	case !fld.IsExported():
+		return ErrUnexported
+	case tag == "":
+		return ErrJoinTag
+	}
+	return nil
+}
+
+// isGroup returns true if the input type is a condition group struct. A condition group is identified by the
+// composition of either the [AnyOf] or [AllOf] types.
+func isGroup(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Struct {
+		return false
 	}
+	for i := 0; i < typ.NumField(); i++ {
+		switch typ.Field(i).Type {
+		case reflect.TypeOf(AnyOf{}), reflect.TypeOf(AllOf{}):
+			return true
+		}
+	}
+	return false
+}
 
-	return stmt, bindings, completion
+// prepareGroup returns the condition tree described by the supplied condition group struct type. Each [Conditions]
+// or [Optional] field is a branch of the group while nested struct fields, including embedded structs, contribute a
+// single branch containing their own conditions.
+func prepareGroup(typ reflect.Type) condition {
+	group := condition{operator: operatorAnd}
+	for i := 0; i < typ.NumField(); i++ {
+		fld := typ.Field(i)
+		switch {
+		case fld.Type == reflect.TypeOf(AnyOf{}):
+			group.operator = operatorOr
+		case fld.Type == reflect.TypeOf(AllOf{}):
+			group.operator = operatorAnd
+		case fld.Type == reflect.TypeOf(Conditions{}):
+			group.branches = append(group.branches, condition{expr: fld.Tag.Get("q")})
+		case fld.Type == reflect.TypeOf(Optional{}):
+			group.branches = append(group.branches, condition{expr: fld.Tag.Get("q"), optional: true})
+		case fld.Type.Kind() == reflect.Struct:
+			if branch := prepareGroup(fld.Type); !branch.empty() {
+				group.branches = append(group.branches, branch)
+			}
+		}
+	}
+	return group
 }
 
 // hasMany returns true if the input type produces a query that contains a many relationship.
 func hasMany(src reflect.Type) bool {
+	if src == nil || scannable(src) {
+		return false
+	}
 	switch src.Kind() {
 	case reflect.Ptr:
 		return hasMany(src.Elem())
 	case reflect.Slice:
-		return true
+		return !isBytes(src)
 	case reflect.Struct:
 		for i := 0; i < src.NumField(); i++ {
 			if hasMany(src.Field(i).Type) {
@@ -336,6 +976,27 @@ func hasMany(src reflect.Type) bool {
 	return false
 }
 
+// isBytes returns true if the input type is a byte slice. Byte slices are scanned as columns rather than describing a
+// many relationship.
+func isBytes(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8
+}
+
+// scanDest returns the destination used to scan into the field identified by the supplied pointer. Byte slice types
+// which do not implement [sql.Scanner], such as [sql.RawBytes], are scanned as a []byte so that the bytes are copied
+// rather than referencing memory owned by the driver.
+func scanDest(ptr reflect.Value) any {
+	if isBytes(ptr.Elem().Type()) && !ptr.Type().Implements(scannerType) && ptr.Type().ConvertibleTo(bytesType) {
+		return ptr.Convert(bytesType).Interface()
+	}
+	return ptr.Interface()
+}
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	bytesType   = reflect.TypeOf((*[]byte)(nil))
+)
+
 // appendFn returns a function that will call both the supplied head and tail functions. The supplied functions may
 // be nil.
 func appendFn(head, tail func(*rowRef, reflect.Value)) func(*rowRef, reflect.Value) {
@@ -4,15 +4,17 @@ package query_test
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/adamkeys/query"
 	"github.com/google/go-cmp/cmp"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 func TestAllBasicSelect(t *testing.T) {
@@ -99,6 +101,22 @@ func TestAllGroupCount(t *testing.T) {
 	}
 }
 
+func TestAllHaving(t *testing.T) {
+	type users struct {
+		query.GroupBy `q:"SUBSTR(name, 1, 1)"`
+		query.Having  `q:"COUNT(*) > ?"`
+
+		Count int `q:"COUNT(*) AS c"`
+	}
+	results, err := query.All(context.Background(), db, func(u users) int { return u.Count }, 1)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if diff := cmp.Diff([]int{3}, results); diff != "" {
+		t.Error(diff)
+	}
+}
+
 func TestAllConditions(t *testing.T) {
 	type users struct {
 		query.Conditions `q:"name = ?"`
@@ -365,310 +383,2046 @@ func TestAllJoinManySharedRow(t *testing.T) {
 	}
 }
 
-func TestAllJoinManyTagRequired(t *testing.T) {
-	defer func() {
-		if err := recover(); err == nil {
-			t.Error("expected many relationship without ON conditions to panic")
-		}
-	}()
-
+func TestAllJoinConditionsCombined(t *testing.T) {
 	type users struct {
-		Addresses []struct{ City string }
-	}
-	query.All(context.Background(), db, query.Identity[users])
-}
+		query.Conditions `q:"users.name = 'Bob'"`
 
-func TestAllLimit(t *testing.T) {
-	type users struct {
-		query.Limit `q:"1"`
+		Name      string `q:"users.name"`
+		Addresses struct {
+			query.Conditions `q:"city = 'New York'"`
 
-		Name string
+			City string `q:"city"`
+		} `q:"users.address_id = addresses.id"`
 	}
 	results, err := query.All(context.Background(), db, query.Identity[users])
 	if err != nil {
 		t.Fatalf("failed to get: %v", err)
 	}
 
-	if len(results) != 1 {
-		t.Errorf("unexpected number of results; got: %v", len(results))
+	if len(results) != 1 || results[0].Name != "Bob" {
+		t.Errorf("unexpected results; got: %v", results)
 	}
 }
 
-func TestAllOffset(t *testing.T) {
+func TestAllAnyOf(t *testing.T) {
 	type users struct {
-		query.Limit  `q:"10"`
-		query.Offset `q:"100"`
+		query.OrderBy `q:"name"`
 
+		Names struct {
+			query.AnyOf
+			Bob  query.Conditions `q:"name = 'Bob'"`
+			John query.Conditions `q:"name = 'John'"`
+		}
 		Name string
 	}
-	results, err := query.All(context.Background(), db, query.Identity[users])
+	results, err := query.All(context.Background(), db, func(u users) string { return u.Name })
 	if err != nil {
 		t.Fatalf("failed to get: %v", err)
 	}
 
-	if len(results) != 0 {
-		t.Errorf("unexpected number of results; got: %v", len(results))
+	exp := []string{"Bob", "John"}
+	if diff := cmp.Diff(exp, results); diff != "" {
+		t.Error(diff)
 	}
 }
 
-func TestAllComposition(t *testing.T) {
-	type BaseUser struct {
-		ID   string
-		Name sql.NullString
-	}
+func TestAllNestedGroups(t *testing.T) {
 	type users struct {
-		query.OrderBy `q:"name ASC"`
+		query.OrderBy `q:"name"`
 
-		BaseUser
+		Filter struct {
+			query.AnyOf
+			Bob   query.Conditions `q:"name = 'Bob'"`
+			Named struct {
+				query.AllOf
+				Prefix query.Conditions `q:"name LIKE 'J%'"`
+				Length query.Conditions `q:"LENGTH(name) = 4"`
+			}
+		}
+		Name string
 	}
-	results, err := query.All(context.Background(), db, query.Identity[users])
+	results, err := query.All(context.Background(), db, func(u users) string { return u.Name })
 	if err != nil {
 		t.Fatalf("failed to get: %v", err)
 	}
 
-	names := make([]string, len(results))
-	for i, user := range results {
-		names[i] = user.Name.String
-	}
-	exp := []string{"", "Bob", "Gary", "James", "Joe", "John"}
-	if diff := cmp.Diff(exp, names); diff != "" {
+	exp := []string{"Bob", "John"}
+	if diff := cmp.Diff(exp, results); diff != "" {
 		t.Error(diff)
 	}
 }
 
-func TestAllCompositionDefinedTable(t *testing.T) {
-	type BaseUser struct {
-		query.Table `q:"users"`
-		ID          string
-		Name        sql.NullString
+func TestAllGroupComposition(t *testing.T) {
+	type garyFilter struct {
+		query.Conditions `q:"name LIKE 'G%'"`
+		Length           query.Conditions `q:"LENGTH(name) = 4"`
 	}
-	type usersQuery struct {
-		query.OrderBy `q:"name ASC"`
+	type nameFilter struct {
+		query.AnyOf
+		garyFilter
+		Bob query.Conditions `q:"name = 'Bob'"`
+	}
+	type users struct {
+		query.OrderBy    `q:"name"`
+		query.Conditions `q:"name IS NOT NULL"`
 
-		BaseUser
+		nameFilter
+		Name string
 	}
-	results, err := query.All(context.Background(), db, query.Identity[usersQuery])
+	results, err := query.All(context.Background(), db, func(u users) string { return u.Name })
 	if err != nil {
 		t.Fatalf("failed to get: %v", err)
 	}
 
-	names := make([]string, len(results))
-	for i, user := range results {
-		names[i] = user.Name.String
-	}
-	exp := []string{"", "Bob", "Gary", "James", "Joe", "John"}
-	if diff := cmp.Diff(exp, names); diff != "" {
+	exp := []string{"Bob", "Gary"}
+	if diff := cmp.Diff(exp, results); diff != "" {
 		t.Error(diff)
 	}
 }
 
-func TestAllCompositionJoinMany(t *testing.T) {
-	type BaseAddresses struct {
-		query.Table `q:"addresses"`
-		City        string
-		Users       []struct {
-			query.Conditions `q:"name = 'John' OR name = 'Bob'"`
-			query.OrderBy    `q:"name"`
+func TestAllOptional(t *testing.T) {
+	type users struct {
+		query.OrderBy  `q:"name"`
+		query.Optional `q:"name = :name"`
 
-			Name string
-		} `q:"users.address_id = addresses.id"`
-	}
-	type addresses struct {
-		BaseAddresses
+		Name sql.NullString
 	}
-	results, err := query.All(context.Background(), db, query.Identity[addresses])
+	results, err := query.All(context.Background(), db, query.Identity[users], sql.Named("name", "Bob"))
 	if err != nil {
 		t.Fatalf("failed to get: %v", err)
 	}
+	if len(results) != 1 || results[0].Name.String != "Bob" {
+		t.Errorf("unexpected results; got: %v", results)
+	}
 
-	addr := addresses{}
-	addr.City = "New York"
-	for _, name := range []string{"Bob", "John"} {
-		addr.Users = append(addr.Users, struct {
-			query.Conditions `q:"name = 'John' OR name = 'Bob'"`
-			query.OrderBy    `q:"name"`
-			Name             string
-		}{Name: name})
+	results, err = query.All(context.Background(), db, query.Identity[users])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
 	}
-	if diff := cmp.Diff([]addresses{addr}, results); diff != "" {
-		t.Error(diff)
+	if len(results) != 6 {
+		t.Errorf("unexpected number of results; got: %d", len(results))
 	}
 }
 
-func TestAllCompositionProperties(t *testing.T) {
-	type BaseUser struct {
-		query.Conditions `q:"name = 'Bob' OR name = 'James'"`
-		query.OrderBy    `q:"name ASC"`
+func TestAllOptionalNil(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name IS NOT NULL"`
+		query.Optional   `q:"name = :name"`
 
-		ID   string
-		Name sql.NullString
+		Name string
 	}
+	var name *string
+	results, err := query.All(context.Background(), db, query.Identity[users], sql.Named("name", name))
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if len(results) != 5 {
+		t.Errorf("unexpected number of results; got: %d", len(results))
+	}
+}
+
+func TestAllOptionalGroup(t *testing.T) {
 	type users struct {
-		query.Conditions `q:"name = 'Bob'"`
+		query.OrderBy `q:"name"`
 
-		BaseUser
+		Names struct {
+			query.AnyOf
+			First  query.Optional `q:"name = :first"`
+			Second query.Optional `q:"name = :second"`
+		}
+		Name sql.NullString
 	}
-	results, err := query.All(context.Background(), db, query.Identity[users])
+	results, err := query.All(context.Background(), db, func(u users) string { return u.Name.String },
+		sql.Named("first", "Gary"), sql.Named("second", "Joe"))
 	if err != nil {
 		t.Fatalf("failed to get: %v", err)
 	}
+	if diff := cmp.Diff([]string{"Gary", "Joe"}, results); diff != "" {
+		t.Error(diff)
+	}
 
-	names := make([]string, len(results))
-	for i, user := range results {
-		names[i] = user.Name.String
+	results, err = query.All(context.Background(), db, func(u users) string { return u.Name.String },
+		sql.Named("second", "Joe"))
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
 	}
-	exp := []string{"Bob"}
-	if diff := cmp.Diff(exp, names); diff != "" {
+	if diff := cmp.Diff([]string{"Joe"}, results); diff != "" {
 		t.Error(diff)
 	}
 }
 
-func TestAllCompositionGroup(t *testing.T) {
-	type BaseUser struct {
-		query.GroupBy `q:"SUBSTR(name, 1, 1)"`
-		query.OrderBy `q:"c DESC"`
+func TestAllNamed(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name LIKE :prefix"`
+		query.OrderBy    `q:"name"`
+		query.Limit      `q:":limit"`
 
-		Count int `q:"COUNT(*) AS c"`
+		Name string
 	}
-	type users struct {
-		BaseUser
+	type params struct {
+		Prefix string
+		Limit  int
 	}
-	results, err := query.All(context.Background(), db, func(u users) int { return u.Count })
+	results, err := query.AllNamed(context.Background(), db, func(u users) string { return u.Name }, params{Prefix: "J%", Limit: 2})
 	if err != nil {
 		t.Fatalf("failed to get: %v", err)
 	}
-	if len(results) == 0 {
-		t.Fatal("expected results")
+	if diff := cmp.Diff([]string{"James", "Joe"}, results); diff != "" {
+		t.Error(diff)
 	}
+}
 
-	exp := []int{3, 1, 1, 1}
-	if diff := cmp.Diff(exp, results); diff != "" {
+func TestAllNamedMap(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name = :name"`
+
+		Name string
+	}
+	params := map[string]any{"name": "Bob"}
+	results, err := query.AllNamed(context.Background(), db, func(u users) string { return u.Name }, params)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if diff := cmp.Diff([]string{"Bob"}, results); diff != "" {
 		t.Error(diff)
 	}
 }
 
-func TestAllCompositionJoin(t *testing.T) {
-	type BaseUser struct {
-		Addresses struct {
-			City string `q:"city"`
-		} `q:"users.address_id = addresses.id"`
+func TestAllNamedPositional(t *testing.T) {
+	var logged []any
+	dbh := query.DB{
+		DB:      db,
+		Options: &query.Options{Logger: func(query string, args []any) { logged = args }},
 	}
 	type users struct {
-		query.OrderBy `q:"name"`
+		query.Conditions `q:"name = ?"`
+		query.Optional   `q:"id = :id"`
+		query.Limit      `q:"?"`
 
-		BaseUser
+		Name string
 	}
-	results, err := query.All(context.Background(), db, query.Identity[users])
+	results, err := query.All(context.Background(), dbh, func(u users) string { return u.Name }, "Bob", sql.Named("id", 5), 10)
 	if err != nil {
 		t.Fatalf("failed to get: %v", err)
 	}
-	if len(results) == 0 {
-		t.Fatal("expected results")
-	}
-
-	exp := users{
-		BaseUser: BaseUser{
-			Addresses: struct {
-				City string `q:"city"`
-			}{"New York"},
-		},
+	if diff := cmp.Diff([]string{"Bob"}, results); diff != "" {
+		t.Error(diff)
 	}
-	if diff := cmp.Diff(exp, results[0]); diff != "" {
+	if diff := cmp.Diff([]any{"Bob", 5, 10}, logged); diff != "" {
 		t.Error(diff)
 	}
 }
 
-func TestAllInvalidField(t *testing.T) {
+func TestAllNamedDriver(t *testing.T) {
+	var logged []any
+	dbh := query.DB{
+		DB:      db,
+		Options: &query.Options{Logger: func(query string, args []any) { logged = args }},
+	}
 	type users struct {
-		Name sql.NullString `q:"nam"`
+		query.Conditions `q:"name = @name"`
+		query.Optional   `q:"id = :id"`
+
+		Name string
 	}
-	_, err := query.All(context.Background(), db, query.Identity[users])
-	if err == nil || err.Error() != "query: no such column: nam" {
-		t.Errorf("unexpected error; got: %v", err)
+	results, err := query.All(context.Background(), dbh, func(u users) string { return u.Name }, sql.Named("name", "Bob"), sql.Named("id", nil))
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
 	}
-}
-
-func TestAllInvalidType(t *testing.T) {
-	type foo struct{}
-	type users struct {
-		Name foo `q:"name"`
+	if diff := cmp.Diff([]string{"Bob"}, results); diff != "" {
+		t.Error(diff)
 	}
-	_, err := query.All(context.Background(), db, query.Identity[users])
-	if err == nil || !strings.Contains(err.Error(), "unsupported Scan") {
-		t.Errorf("unexpected error; got: %v", err)
+	if len(logged) != 1 || logged[0].(sql.NamedArg).Name != "name" {
+		t.Errorf("expected only the driver named argument to be sent; got: %v", logged)
 	}
 }
 
-func TestOneConditions(t *testing.T) {
+func TestAllNamedDollar(t *testing.T) {
+	var logged string
+	dbh := query.DB{
+		DB: db,
+		Options: &query.Options{
+			Placeholder: query.Dollar,
+			Logger:      func(query string, args []any) { logged = query },
+		},
+	}
 	type users struct {
-		query.Conditions `q:"name = ?"`
+		query.Conditions `q:"name = :name OR LENGTH(name) = :length"`
+		query.Optional   `q:"name != :name"`
 
-		ID   string         `q:"id"`
-		Name sql.NullString `q:"name"`
+		Name string
 	}
-	user, err := query.One(context.Background(), db, query.Identity[users], "Bob")
+	params := map[string]any{"name": "Bob", "length": 3}
+	results, err := query.AllNamed(context.Background(), dbh, func(u users) string { return u.Name }, params)
 	if err != nil {
 		t.Fatalf("failed to get: %v", err)
 	}
+	if diff := cmp.Diff([]string{"Joe"}, results); diff != "" {
+		t.Error(diff)
+	}
 
-	if user.Name.String != "Bob" {
-		t.Errorf("unexpected user; got: %v", user)
+	const exp = "SELECT users.name FROM users WHERE (name = $1 OR LENGTH(name) = $2) AND (name != $1)"
+	if logged != exp {
+		t.Errorf("expected query to be: %q; got: %q", exp, logged)
 	}
 }
 
-func TestOneCount(t *testing.T) {
+func TestAllStrict(t *testing.T) {
+	var logged string
+	dbh := query.DB{
+		DB: db,
+		Options: &query.Options{
+			Strict: true,
+			Logger: func(query string, args []any) { logged = query },
+		},
+	}
 	type users struct {
-		Count int `q:"COUNT(*)"`
+		query.Conditions `q:"name = ?"`
+
+		ID   int    `q:"id"`
+		Name string `q:"UPPER(name) AS upper_name"`
 	}
-	count, err := query.One(context.Background(), db, func(u users) int { return u.Count })
+	results, err := query.All(context.Background(), dbh, func(u users) string { return u.Name }, "Bob")
 	if err != nil {
 		t.Fatalf("failed to get: %v", err)
 	}
+	if diff := cmp.Diff([]string{"BOB"}, results); diff != "" {
+		t.Error(diff)
+	}
 
-	if count != 6 {
-		t.Errorf("unexpected count; got: %v", count)
+	const exp = "SELECT id AS c0, UPPER(name) AS upper_name FROM users WHERE (name = ?)"
+	if logged != exp {
+		t.Errorf("expected query to be: %q; got: %q", exp, logged)
 	}
 }
 
-func TestOneTableName(t *testing.T) {
-	type userTable struct {
-		query.Table      `q:"users"`
+func TestAllStrictMismatch(t *testing.T) {
+	dbh := query.DB{DB: db, Options: &query.Options{Strict: true}}
+	type users struct {
+		ID   int    `q:"id, name"`
+		Name string `q:"name"`
+	}
+	_, err := query.All(context.Background(), dbh, query.Identity[users])
+	const exp = `columns: result column "id" does not match column "c0" for field ID`
+	if err == nil || err.Error() != exp {
+		t.Errorf("expected error %q; got: %v", exp, err)
+	}
+}
+
+func TestOneStrict(t *testing.T) {
+	dbh := query.DB{DB: db, Options: &query.Options{Strict: true}}
+	type users struct {
 		query.Conditions `q:"name = ?"`
 
-		Name sql.NullString `q:"name"`
+		Name string `q:"name"`
 	}
-	result, err := query.One(context.Background(), db, query.Identity[userTable], "Bob")
+	result, err := query.One(context.Background(), dbh, func(u users) string { return u.Name }, "Bob")
 	if err != nil {
 		t.Fatalf("failed to get: %v", err)
 	}
+	if result != "Bob" {
+		t.Errorf("expected Bob; got: %q", result)
+	}
 
-	if result.Name.String != "Bob" {
-		t.Errorf("unexpected result; got: %v", result)
+	_, err = query.One(context.Background(), dbh, query.Identity[users], "Nobody")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected no rows error; got: %v", err)
 	}
 }
 
-func TestOneJoin(t *testing.T) {
+func TestSQL(t *testing.T) {
+	var (
+		logged     string
+		loggedArgs []any
+	)
+	options := &query.Options{
+		Placeholder: query.Dollar,
+		Strict:      true,
+		Logger:      func(query string, args []any) { logged, loggedArgs = query, args },
+		Rewrite: func(stmt *query.Statement) {
+			stmt.Conditions = append(stmt.Conditions, query.Condition{Expr: "users.id > 0"})
+		},
+	}
 	type users struct {
-		query.OrderBy `q:"name DESC"`
+		query.Conditions `q:"name LIKE :prefix AND id IN (:ids)"`
+		query.Optional   `q:"name != :name"`
+		query.Limit      `q:":limit"`
 
-		Name      string `q:"name"`
-		Addresses struct {
-			City string `q:"city"`
-		} `q:"users.address_id = addresses.id"`
+		Name string
 	}
-	result, err := query.One(context.Background(), db, query.Identity[users])
+	args := []any{sql.Named("prefix", "J%"), sql.Named("ids", []int{1, 2}), sql.Named("limit", 10)}
+	generated, err := query.SQL[users](options, args...)
 	if err != nil {
-		t.Fatalf("failed to get: %v", err)
+		t.Fatalf("failed to generate: %v", err)
 	}
-	exp := users{Name: "John", Addresses: struct {
-		City string `q:"city"`
-	}{"New York"}}
-	if diff := cmp.Diff(exp, result); diff != "" {
+	got, gotArgs := generated.SQL, generated.Args
+	const exp = "SELECT users.name AS c0 FROM users WHERE (name LIKE $1 AND id IN ($2, $3)) AND (users.id > 0) LIMIT $4"
+	if got != exp {
+		t.Errorf("expected query to be: %q; got: %q", exp, got)
+	}
+	if diff := cmp.Diff([]any{"J%", 1, 2, 10}, gotArgs); diff != "" {
 		t.Error(diff)
 	}
-}
+	if diff := cmp.Diff([]string{"prefix", "ids", "name", "limit"}, generated.Named); diff != "" || generated.Positional != 0 {
+		t.Errorf("unexpected parameters: %d positional, %s", generated.Positional, diff)
+	}
 
-func TestOneNestedJoin(t *testing.T) {
+	// The query matches the query sent by All.
+	if _, err := query.All(context.Background(), query.DB{DB: db, Options: options}, query.Identity[users], args...); err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if logged != got {
+		t.Errorf("expected query to be: %q; got: %q", logged, got)
+	}
+	if diff := cmp.Diff(loggedArgs, gotArgs); diff != "" {
+		t.Error(diff)
+	}
+
+	_, err = query.SQL[users](nil, sql.Named("prefix", "J%"))
+	if err == nil || err.Error() != `bind: missing argument for parameter "ids"` {
+		t.Errorf("unexpected error; got: %v", err)
+	}
+}
+
+func TestSQLNamer(t *testing.T) {
+	type users struct {
+		UserName string
+	}
+	generated, err := query.SQL[users](&query.Options{Namer: testNamer{}})
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	const exp = "SELECT users.name FROM users"
+	if generated.SQL != exp || len(generated.Args) != 0 {
+		t.Errorf("expected query to be: %q with no arguments; got: %q with %v", exp, generated.SQL, generated.Args)
+	}
+}
+
+func TestSQLPositional(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name LIKE ? AND id > ?"`
+
+		Name string
+	}
+	generated, err := query.SQL[users](nil)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	const exp = "SELECT users.name FROM users WHERE (name LIKE ? AND id > ?)"
+	if generated.SQL != exp || generated.Positional != 2 || len(generated.Args) != 0 {
+		t.Errorf("expected query %q expecting 2 arguments; got: %+v", exp, generated)
+	}
+}
+
+func TestAllRewrite(t *testing.T) {
+	var logged []string
+	dbh := query.DB{
+		DB: db,
+		Options: &query.Options{
+			Logger: func(query string, args []any) { logged = append(logged, query) },
+			Rewrite: func(stmt *query.Statement) {
+				stmt.From = "users AS u"
+				stmt.Conditions = append(stmt.Conditions, query.Condition{Any: true, Branches: []query.Condition{
+					{Expr: "u.name = :first"},
+					{Expr: "u.name = :second"},
+				}})
+			},
+		},
+	}
+	type users struct {
+		query.Table      `q:"users"`
+		query.Conditions `q:"LENGTH(name) > ?"`
+		query.OrderBy    `q:"name"`
+
+		Name string `q:"name"`
+	}
+	args := []any{2, sql.Named("first", "Bob"), sql.Named("second", "John")}
+	results, err := query.All(context.Background(), dbh, func(u users) string { return u.Name }, args...)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if diff := cmp.Diff([]string{"Bob", "John"}, results); diff != "" {
+		t.Error(diff)
+	}
+	count, err := query.Count[users](context.Background(), dbh, args...)
+	if err != nil {
+		t.Fatalf("failed to count: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected count to be 2; got: %d", count)
+	}
+
+	exp := []string{
+		"SELECT name FROM users AS u WHERE (LENGTH(name) > ?) AND ((u.name = ?) OR (u.name = ?)) ORDER BY name",
+		"SELECT COUNT(*) FROM users AS u WHERE (LENGTH(name) > ?) AND ((u.name = ?) OR (u.name = ?))",
+	}
+	if diff := cmp.Diff(exp, logged); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAllRewriteArgs(t *testing.T) {
+	var logged []string
+	dbh := query.DB{
+		DB: db,
+		Options: &query.Options{
+			Logger: func(query string, args []any) { logged = append(logged, query) },
+			Rewrite: func(stmt *query.Statement) {
+				stmt.From = "addresses AS a"
+				for i := range stmt.Columns {
+					stmt.Columns[i].Expr = strings.ReplaceAll(stmt.Columns[i].Expr, "addresses.", "a.")
+				}
+				for i := range stmt.Joins {
+					stmt.Joins[i].On = strings.ReplaceAll(stmt.Joins[i].On, "addresses.", "a.")
+				}
+				stmt.Conditions = append(stmt.Conditions, query.Condition{Expr: "a.country_id = ?", Args: []any{1}})
+			},
+		},
+	}
+	type addresses struct {
+		query.Conditions `q:"users.name LIKE ?"`
+
+		City  string
+		Users []struct {
+			Name string
+		} `q:"users.address_id = addresses.id"`
+	}
+	results, err := query.All(context.Background(), dbh, query.Identity[addresses], "J%")
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if len(results) != 1 || results[0].City != "New York" || len(results[0].Users) != 3 {
+		t.Errorf("unexpected results: %+v", results)
+	}
+	count, err := query.Count[addresses](context.Background(), dbh, "J%")
+	if err != nil {
+		t.Fatalf("failed to count: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected count to be 1; got: %d", count)
+	}
+
+	exp := []string{
+		"SELECT a.id, a.city, users.id, users.name FROM addresses AS a INNER JOIN users ON users.address_id = a.id " +
+			"WHERE (users.name LIKE ?) AND (a.country_id = ?)",
+		"SELECT COUNT(DISTINCT a.id) FROM addresses AS a INNER JOIN users ON users.address_id = a.id " +
+			"WHERE (users.name LIKE ?) AND (a.country_id = ?)",
+	}
+	if diff := cmp.Diff(exp, logged); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestStatementSQL(t *testing.T) {
+	stmt := query.Statement{
+		Columns: []query.ColumnExpr{{Expr: "users.name"}, {Expr: "COUNT(*)", Alias: "total"}},
+		From:    "users",
+		Joins: []query.Join{
+			{Table: "addresses", On: "users.address_id = addresses.id"},
+			{Table: "countries", On: "addresses.country_id = countries.id", Left: true},
+		},
+		Conditions: []query.Condition{
+			{Expr: "users.id > ?"},
+			{Any: true, Branches: []query.Condition{
+				{Expr: "city = ?"},
+				{Branches: []query.Condition{{Expr: "countries.id = ?"}, {Expr: "city IS NULL"}}},
+			}},
+		},
+		Group:  []string{"users.name"},
+		Having: []query.Condition{{Expr: "COUNT(*) > 1"}},
+		Order:  []string{"total DESC", "users.name"},
+		Limit:  "10",
+		Offset: "20",
+	}
+	const exp = "SELECT users.name, COUNT(*) AS total FROM users" +
+		" INNER JOIN addresses ON users.address_id = addresses.id" +
+		" LEFT JOIN countries ON addresses.country_id = countries.id" +
+		" WHERE (users.id > ?) AND ((city = ?) OR ((countries.id = ?) AND (city IS NULL)))" +
+		" GROUP BY users.name HAVING (COUNT(*) > 1) ORDER BY total DESC, users.name LIMIT 10 OFFSET 20"
+	if got := stmt.SQL(); got != exp {
+		t.Errorf("expected query to be: %q; got: %q", exp, got)
+	}
+}
+
+func TestAllNamedMissing(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name = :name"`
+
+		Name string
+	}
+	_, err := query.AllNamed(context.Background(), db, query.Identity[users], map[string]any{"id": 1})
+	if err == nil || err.Error() != `bind: missing argument for parameter "name"` {
+		t.Errorf("unexpected error; got: %v", err)
+	}
+}
+
+func TestAllUnusedArgs(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name = ?"`
+
+		Name string
+	}
+	_, err := query.All(context.Background(), db, query.Identity[users], "John", 1)
+	if err == nil || err.Error() != "bind: unused positional arguments: 2" {
+		t.Errorf("unexpected error; got: %v", err)
+	}
+	_, err = query.All(context.Background(), db, query.Identity[users], "John", sql.Named("id", 1), 1)
+	if err == nil || err.Error() != "bind: unused positional arguments: 2" {
+		t.Errorf("unexpected error; got: %v", err)
+	}
+	_, err = query.Count[users](context.Background(), db, "John", 1)
+	if err == nil || err.Error() != "bind: unused positional arguments: 2" {
+		t.Errorf("unexpected error; got: %v", err)
+	}
+}
+
+func TestAllWith(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name LIKE :prefix"`
+		query.Optional   `q:"LENGTH(name) = :length"`
+		query.OrderBy    `q:"name"`
+
+		Name string
+	}
+	type params struct {
+		Prefix string
+		Length *int
+	}
+	results, err := query.AllWith(context.Background(), db, func(u users) string { return u.Name }, params{Prefix: "J%"})
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if diff := cmp.Diff([]string{"James", "Joe", "John"}, results); diff != "" {
+		t.Error(diff)
+	}
+
+	length := 4
+	results, err = query.AllWith(context.Background(), db, func(u users) string { return u.Name }, params{Prefix: "J%", Length: &length})
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if diff := cmp.Diff([]string{"John"}, results); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAllWithMismatch(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name = :name AND id = :id"`
+
+		Name string
+	}
+	type missing struct {
+		Name string
+	}
+	// The check is cached after the first call, so the error is reported on each call.
+	for i := 0; i < 2; i++ {
+		_, err := query.AllWith(context.Background(), db, query.Identity[users], missing{})
+		if err == nil || err.Error() != "bind: missing arguments for parameters: id" {
+			t.Errorf("unexpected error; got: %v", err)
+		}
+	}
+
+	type extra struct {
+		ID    int
+		Name  string
+		Limit int
+	}
+	_, err := query.AllWith(context.Background(), db, query.Identity[users], extra{})
+	if err == nil || err.Error() != "bind: unused arguments: limit" {
+		t.Errorf("unexpected error; got: %v", err)
+	}
+}
+
+func TestAllInList(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"id IN (?)"`
+		query.OrderBy    `q:"name"`
+
+		Name string
+	}
+	results, err := query.All(context.Background(), db, func(u users) string { return u.Name }, []int{1, 3, 5})
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if diff := cmp.Diff([]string{"Bob", "Gary", "John"}, results); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAllInListEmpty(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"id IN (:ids)"`
+
+		Name string
+	}
+	results, err := query.All(context.Background(), db, query.Identity[users], sql.Named("ids", []int{}))
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("unexpected number of results; got: %d", len(results))
+	}
+
+	type others struct {
+		query.Table      `q:"users"`
+		query.Conditions `q:"id NOT IN (:ids)"`
+
+		Name sql.NullString
+	}
+	all, err := query.All(context.Background(), db, query.Identity[others], sql.Named("ids", []int{}))
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if len(all) != 6 {
+		t.Errorf("unexpected number of results; got: %d", len(all))
+	}
+}
+
+func TestAllInListOptionalNil(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name IS NOT NULL"`
+		query.Optional   `q:"users.id IN (:ids)"`
+
+		Name string
+	}
+	results, err := query.All(context.Background(), db, query.Identity[users], sql.Named("ids", []int(nil)))
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if len(results) != 5 {
+		t.Errorf("unexpected number of results; got: %d", len(results))
+	}
+
+	results, err = query.All(context.Background(), db, query.Identity[users], sql.Named("ids", []int{}))
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("unexpected number of results for an empty list; got: %d", len(results))
+	}
+}
+
+func TestAllInListEmptyPredicate(t *testing.T) {
+	var logged string
+	dbh := query.DB{
+		DB:      db,
+		Options: &query.Options{Logger: func(query string, args []any) { logged = query }},
+	}
+	type users struct {
+		query.Conditions `q:"LOWER(users.name) NOT IN (:names) AND (users.id IN (:ids) OR users.id = :id)"`
+
+		Name string
+	}
+	_, err := query.All(context.Background(), dbh, query.Identity[users],
+		sql.Named("names", []string{}), sql.Named("ids", []int{}), sql.Named("id", 1))
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	const exp = "SELECT users.name FROM users WHERE (1 = 1 AND (1 = 0 OR users.id = ?))"
+	if logged != exp {
+		t.Errorf("expected query to be: %q; got: %q", exp, logged)
+	}
+
+	type ambiguous struct {
+		query.Table      `q:"users"`
+		query.Conditions `q:"users.id + 1 IN (:ids)"`
+
+		Name string
+	}
+	_, err = query.All(context.Background(), db, query.Identity[ambiguous], sql.Named("ids", []int{}))
+	if err == nil || err.Error() != "bind: empty list bound to :ids cannot be replaced by a constant predicate" {
+		t.Errorf("unexpected error; got: %v", err)
+	}
+}
+
+func TestAllInListDollar(t *testing.T) {
+	var (
+		logged string
+		args   []any
+	)
+	dbh := query.DB{
+		DB: db,
+		Options: &query.Options{Logger: func(query string, a []any) {
+			logged, args = query, a
+		}},
+	}
+	type users struct {
+		query.Conditions `q:"id IN ($1) AND name != $2"`
+		query.Limit      `q:"$3"`
+
+		Name string
+	}
+	results, err := query.All(context.Background(), dbh, func(u users) string { return u.Name }, []string{"1", "2"}, "John", 10)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if diff := cmp.Diff([]string{"James"}, results); diff != "" {
+		t.Error(diff)
+	}
+
+	const exp = "SELECT users.name FROM users WHERE (id IN ($1, $2) AND name != $3) LIMIT $4"
+	if logged != exp {
+		t.Errorf("expected query to be: %q; got: %q", exp, logged)
+	}
+	if diff := cmp.Diff([]any{"1", "2", "John", 10}, args); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAllModifiers(t *testing.T) {
+	var logged string
+	dbh := query.DB{
+		DB:      db,
+		Options: &query.Options{Logger: func(query string, args []any) { logged = query }},
+	}
+	type users struct {
+		query.Conditions `q:"name IS NOT NULL"`
+		query.Limit      `q:"1"`
+
+		Name string
+	}
+	results, err := query.All(context.Background(), dbh, func(u users) string { return u.Name },
+		query.Where("name LIKE ?", "J%"), query.Order("name DESC"), query.LimitTo(2), query.OffsetBy(1))
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if diff := cmp.Diff([]string{"Joe", "James"}, results); diff != "" {
+		t.Error(diff)
+	}
+
+	const exp = "SELECT users.name FROM users WHERE (name IS NOT NULL) AND (name LIKE ?) ORDER BY name DESC LIMIT 2 OFFSET 1"
+	if logged != exp {
+		t.Errorf("expected query to be: %q; got: %q", exp, logged)
+	}
+}
+
+func TestAllModifiersReplaceOrder(t *testing.T) {
+	var logged string
+	dbh := query.DB{
+		DB:      db,
+		Options: &query.Options{Logger: func(query string, args []any) { logged = query }},
+	}
+	type users struct {
+		query.Conditions `q:"users.name IS NOT NULL"`
+		query.OrderBy    `q:"users.name"`
+
+		Name      string
+		Addresses struct {
+			query.OrderBy `q:"addresses.city"`
+
+			City string
+		} `q:"users.address_id = addresses.id"`
+	}
+	results, err := query.All(context.Background(), dbh, func(u users) string { return u.Name },
+		query.ReplaceOrder("users.id DESC"))
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if diff := cmp.Diff([]string{"Bob", "Joe", "Gary", "James", "John"}, results); diff != "" {
+		t.Error(diff)
+	}
+
+	const exp = "SELECT users.name, addresses.city FROM users INNER JOIN addresses ON users.address_id = addresses.id " +
+		"WHERE (users.name IS NOT NULL) ORDER BY users.id DESC"
+	if logged != exp {
+		t.Errorf("expected query to be: %q; got: %q", exp, logged)
+	}
+}
+
+func TestAllModifiersArgs(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name != ?"`
+		query.OrderBy    `q:"name"`
+		query.Limit      `q:"?"`
+
+		Name string
+	}
+	results, err := query.All(context.Background(), db, func(u users) string { return u.Name },
+		"John", query.Where("name LIKE ? OR name = ?", "J%", "Bob"), 3)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if diff := cmp.Diff([]string{"Bob", "James", "Joe"}, results); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAllCompositionLimit(t *testing.T) {
+	type BaseUser struct {
+		query.Limit `q:"2"`
+
+		Name string
+	}
+	type users struct {
+		BaseUser
+	}
+	results, err := query.All(context.Background(), db, query.Identity[users])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("unexpected number of results; got: %d", len(results))
+	}
+}
+
+func TestAllSort(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"users.name IS NOT NULL"`
+		query.OrderBy    `q:"users.id"`
+
+		ID        int
+		Name      string
+		Addresses struct {
+			City string
+		} `q:"users.address_id = addresses.id"`
+	}
+	sort, err := query.Sort[users](nil, "city,-name")
+	if err != nil {
+		t.Fatalf("failed to sort: %v", err)
+	}
+	results, err := query.All(context.Background(), db, func(u users) string { return u.Name }, sort)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if diff := cmp.Diff([]string{"John", "Joe", "James", "Gary", "Bob"}, results); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestSortUnknownKey(t *testing.T) {
+	type users struct {
+		Name  string
+		Count int `q:"COUNT(*)"`
+	}
+	for _, spec := range []string{"-password", "COUNT(*)", "name; DROP TABLE users"} {
+		_, err := query.Sort[users](nil, spec)
+		var sortErr *query.SortError
+		if !errors.As(err, &sortErr) {
+			t.Errorf("expected sort error for %q; got: %v", spec, err)
+		}
+	}
+}
+
+func TestSeek(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name IS NOT NULL"`
+
+		ID   int
+		Name string
+	}
+	tests := []struct {
+		sort string
+		exp  []string
+	}{
+		{"name,id", []string{"Bob", "Gary", "James", "Joe", "John"}},
+		{"-name,-id", []string{"John", "Joe", "James", "Gary", "Bob"}},
+		{"-id", []string{"Bob", "Joe", "Gary", "James", "John"}},
+		{"name,-id", []string{"Bob", "Gary", "James", "Joe", "John"}},
+	}
+	for _, test := range tests {
+		var (
+			names []string
+			pages int
+		)
+		keyset := query.Keyset{Sort: test.sort, Size: 2, Secret: []byte("secret")}
+		for {
+			page, err := query.Seek(context.Background(), db, func(u users) string { return u.Name }, keyset)
+			if err != nil {
+				t.Fatalf("failed to seek %q: %v", test.sort, err)
+			}
+			names = append(names, page.Items...)
+			pages++
+			if page.Next == "" {
+				break
+			}
+			keyset.After = page.Next
+		}
+		if diff := cmp.Diff(test.exp, names); diff != "" {
+			t.Errorf("%s: %s", test.sort, diff)
+		}
+		if pages != 3 {
+			t.Errorf("%s: unexpected number of pages; got: %d", test.sort, pages)
+		}
+	}
+}
+
+func TestSeekInvalidCursor(t *testing.T) {
+	type users struct {
+		ID   int
+		Name sql.NullString
+	}
+	keyset := query.Keyset{Sort: "id", Size: 2, Secret: []byte("secret")}
+	page, err := query.Seek(context.Background(), db, query.Identity[users], keyset)
+	if err != nil {
+		t.Fatalf("failed to seek: %v", err)
+	}
+
+	for _, keyset := range []query.Keyset{
+		{Sort: "id", Size: 2, Secret: []byte("other"), After: page.Next},
+		{Sort: "-id", Size: 2, Secret: []byte("secret"), After: page.Next},
+		{Sort: "id", Size: 2, Secret: []byte("secret"), After: "e30." + page.Next[strings.Index(page.Next, ".")+1:]},
+	} {
+		_, err = query.Seek(context.Background(), db, query.Identity[users], keyset)
+		if !errors.Is(err, query.ErrInvalidCursor) {
+			t.Errorf("expected invalid cursor error; got: %v", err)
+		}
+	}
+}
+
+func TestSeekInvalidKeyset(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name IS NOT NULL"`
+
+		ID   int
+		Name reusingScanner
+	}
+	_, err := query.Seek(context.Background(), db, query.Identity[users], query.Keyset{Sort: "id", Size: 2})
+	if err == nil || err.Error() != "seek: a secret is required to sign cursor tokens" {
+		t.Errorf("unexpected error; got: %v", err)
+	}
+
+	keyset := query.Keyset{Sort: "name,id", Size: 2, Secret: []byte("secret")}
+	_, err = query.Seek(context.Background(), db, query.Identity[users], keyset)
+	if err == nil || !strings.HasPrefix(err.Error(), `seek: key "name": `) {
+		t.Errorf("unexpected error; got: %v", err)
+	}
+
+	// Keys are parsed like those of Sort, so only a single direction prefix is accepted.
+	keyset = query.Keyset{Sort: "--id", Size: 2, Secret: []byte("secret")}
+	_, seekErr := query.Seek(context.Background(), db, query.Identity[users], keyset)
+	_, sortErr := query.Sort[users](nil, keyset.Sort)
+	for _, err := range []error{seekErr, sortErr} {
+		var serr *query.SortError
+		if !errors.As(err, &serr) || serr.Key != "-id" {
+			t.Errorf("expected sort error for key -id; got: %v", err)
+		}
+	}
+}
+
+func TestAllPage(t *testing.T) {
+	var logged []string
+	dbh := query.DB{
+		DB:      db,
+		Options: &query.Options{Logger: func(query string, args []any) { logged = append(logged, query) }},
+	}
+	type users struct {
+		query.Conditions `q:"users.name != $1"`
+		query.OrderBy    `q:"name"`
+		query.Limit      `q:"1"`
+
+		Name      string
+		Addresses struct {
+			City string
+		} `q:"users.address_id = addresses.id"`
+	}
+	page, err := query.AllPage(context.Background(), dbh, func(u users) string { return u.Name }, query.Paging{Number: 2, Size: 2}, "Gary")
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	exp := query.Page[string]{Items: []string{"Joe", "John"}, Number: 2, Size: 2, Total: 4, Pages: 2}
+	if diff := cmp.Diff(exp, page); diff != "" {
+		t.Error(diff)
+	}
+
+	expQueries := []string{
+		"SELECT COUNT(*) FROM users INNER JOIN addresses ON users.address_id = addresses.id WHERE (users.name != $1)",
+		"SELECT users.name, addresses.city FROM users INNER JOIN addresses ON users.address_id = addresses.id WHERE (users.name != $1) ORDER BY name LIMIT 2 OFFSET 2",
+	}
+	if diff := cmp.Diff(expQueries, logged); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAllPagePlaceholderLimit(t *testing.T) {
+	type users struct {
+		query.OrderBy `q:"id"`
+		query.Limit   `q:"$1"`
+		query.Offset  `q:"$2"`
+		ID            int
+		Name          string
+	}
+	page, err := query.AllPage(context.Background(), db, func(u users) string { return u.Name }, query.Paging{Number: 1, Size: 2}, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	exp := query.Page[string]{Items: []string{"John", "James"}, Number: 1, Size: 2, Total: 6, Pages: 3}
+	if diff := cmp.Diff(exp, page); diff != "" {
+		t.Error(diff)
+	}
+
+	results, err := query.All(context.Background(), db, func(u users) string { return u.Name }, 10, 0, query.LimitTo(1))
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if diff := cmp.Diff([]string{"John"}, results); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAllPageGroup(t *testing.T) {
+	type users struct {
+		query.GroupBy `q:"SUBSTR(name, 1, 1)"`
+		query.OrderBy `q:"c DESC"`
+
+		Count int `q:"COUNT(*) AS c"`
+	}
+	page, err := query.AllPage(context.Background(), db, func(u users) int { return u.Count }, query.Paging{Number: 1, Size: 2})
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	exp := query.Page[int]{Items: []int{3, 1}, Number: 1, Size: 2, Total: 4, Pages: 2}
+	if diff := cmp.Diff(exp, page); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAllJoinFieldOrder(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"users.name = 'Bob'"`
+
+		Addresses struct {
+			City string
+		} `q:"users.address_id = addresses.id"`
+		Name string
+	}
+	results, err := query.All(context.Background(), db, query.Identity[users])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	exp := users{Name: "Bob"}
+	exp.Addresses.City = "New York"
+	if diff := cmp.Diff([]users{exp}, results); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAllJoinManyTagRequired(t *testing.T) {
+	type users struct {
+		Addresses []struct{ City string }
+	}
+	_, err := query.All(context.Background(), db, query.Identity[users])
+	var serr *query.StructError
+	if !errors.As(err, &serr) || serr.Path != "Addresses" || !errors.Is(err, query.ErrJoinTag) {
+		t.Errorf("expected join tag error; got: %v", err)
+	}
+}
+
+func TestAllJoinTagRequiredPath(t *testing.T) {
+	type users struct {
+		Name      string
+		Addresses struct {
+			City      string
+			Countries struct{ Name string }
+		} `q:"users.address_id = addresses.id"`
+	}
+	_, err := query.One(context.Background(), db, query.Identity[users])
+	const exp = "query: query_test.users.Addresses.Countries: join requires a struct tag describing the join conditions"
+	if err == nil || err.Error() != exp {
+		t.Errorf("expected error %q; got: %v", exp, err)
+	}
+}
+
+func TestAllUnexportedField(t *testing.T) {
+	type users struct {
+		ID   int
+		name string
+	}
+	_, err := query.All(context.Background(), db, query.Identity[users])
+	var serr *query.StructError
+	if !errors.As(err, &serr) || serr.Path != "name" || !errors.Is(err, query.ErrUnexported) {
+		t.Errorf("expected unexported field error; got: %v", err)
+	}
+
+	type joined struct {
+		query.Table `q:"users"`
+		addresses   struct{ City string } `q:"users.address_id = addresses.id"`
+	}
+	_, err = query.One(context.Background(), db, query.Identity[joined])
+	if !errors.As(err, &serr) || serr.Path != "addresses" || !errors.Is(err, query.ErrUnexported) {
+		t.Errorf("expected unexported field error; got: %v", err)
+	}
+}
+
+func TestAllNotStruct(t *testing.T) {
+	_, err := query.All(context.Background(), db, query.Identity[string])
+	if !errors.Is(err, query.ErrNotStruct) {
+		t.Errorf("expected not struct error; got: %v", err)
+	}
+	_, err = query.One(context.Background(), db, query.Identity[any])
+	if !errors.Is(err, query.ErrNotStruct) {
+		t.Errorf("expected not struct error; got: %v", err)
+	}
+	_, err = query.Count[[]int](context.Background(), db)
+	if !errors.Is(err, query.ErrNotStruct) {
+		t.Errorf("expected not struct error; got: %v", err)
+	}
+}
+
+func TestAllLimit(t *testing.T) {
+	type users struct {
+		query.Limit `q:"1"`
+
+		Name string
+	}
+	results, err := query.All(context.Background(), db, query.Identity[users])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Errorf("unexpected number of results; got: %v", len(results))
+	}
+}
+
+func TestAllOffset(t *testing.T) {
+	type users struct {
+		query.Limit  `q:"10"`
+		query.Offset `q:"100"`
+
+		Name string
+	}
+	results, err := query.All(context.Background(), db, query.Identity[users])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	if len(results) != 0 {
+		t.Errorf("unexpected number of results; got: %v", len(results))
+	}
+}
+
+func TestAllComposition(t *testing.T) {
+	type BaseUser struct {
+		ID   string
+		Name sql.NullString
+	}
+	type users struct {
+		query.OrderBy `q:"name ASC"`
+
+		BaseUser
+	}
+	results, err := query.All(context.Background(), db, query.Identity[users])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	names := make([]string, len(results))
+	for i, user := range results {
+		names[i] = user.Name.String
+	}
+	exp := []string{"", "Bob", "Gary", "James", "Joe", "John"}
+	if diff := cmp.Diff(exp, names); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAllCompositionDefinedTable(t *testing.T) {
+	type BaseUser struct {
+		query.Table `q:"users"`
+		ID          string
+		Name        sql.NullString
+	}
+	type usersQuery struct {
+		query.OrderBy `q:"name ASC"`
+
+		BaseUser
+	}
+	results, err := query.All(context.Background(), db, query.Identity[usersQuery])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	names := make([]string, len(results))
+	for i, user := range results {
+		names[i] = user.Name.String
+	}
+	exp := []string{"", "Bob", "Gary", "James", "Joe", "John"}
+	if diff := cmp.Diff(exp, names); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAllCompositionJoinMany(t *testing.T) {
+	type BaseAddresses struct {
+		query.Table `q:"addresses"`
+		City        string
+		Users       []struct {
+			query.Conditions `q:"name = 'John' OR name = 'Bob'"`
+			query.OrderBy    `q:"name"`
+
+			Name string
+		} `q:"users.address_id = addresses.id"`
+	}
+	type addresses struct {
+		BaseAddresses
+	}
+	results, err := query.All(context.Background(), db, query.Identity[addresses])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	addr := addresses{}
+	addr.City = "New York"
+	for _, name := range []string{"Bob", "John"} {
+		addr.Users = append(addr.Users, struct {
+			query.Conditions `q:"name = 'John' OR name = 'Bob'"`
+			query.OrderBy    `q:"name"`
+			Name             string
+		}{Name: name})
+	}
+	if diff := cmp.Diff([]addresses{addr}, results); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAllCompositionProperties(t *testing.T) {
+	type BaseUser struct {
+		query.Conditions `q:"name = 'Bob' OR name = 'James'"`
+		query.OrderBy    `q:"name ASC"`
+
+		ID   string
+		Name sql.NullString
+	}
+	type users struct {
+		query.Conditions `q:"name = 'Bob'"`
+
+		BaseUser
+	}
+	results, err := query.All(context.Background(), db, query.Identity[users])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	names := make([]string, len(results))
+	for i, user := range results {
+		names[i] = user.Name.String
+	}
+	exp := []string{"Bob"}
+	if diff := cmp.Diff(exp, names); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAllCompositionGroup(t *testing.T) {
+	type BaseUser struct {
+		query.GroupBy `q:"SUBSTR(name, 1, 1)"`
+		query.OrderBy `q:"c DESC"`
+
+		Count int `q:"COUNT(*) AS c"`
+	}
+	type users struct {
+		BaseUser
+	}
+	results, err := query.All(context.Background(), db, func(u users) int { return u.Count })
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if len(results) == 0 {
+		t.Fatal("expected results")
+	}
+
+	exp := []int{3, 1, 1, 1}
+	if diff := cmp.Diff(exp, results); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAllCompositionJoin(t *testing.T) {
+	type BaseUser struct {
+		Addresses struct {
+			City string `q:"city"`
+		} `q:"users.address_id = addresses.id"`
+	}
+	type users struct {
+		query.OrderBy `q:"name"`
+
+		BaseUser
+	}
+	results, err := query.All(context.Background(), db, query.Identity[users])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if len(results) == 0 {
+		t.Fatal("expected results")
+	}
+
+	exp := users{
+		BaseUser: BaseUser{
+			Addresses: struct {
+				City string `q:"city"`
+			}{"New York"},
+		},
+	}
+	if diff := cmp.Diff(exp, results[0]); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAllInvalidField(t *testing.T) {
+	type users struct {
+		Name sql.NullString `q:"nam"`
+	}
+	_, err := query.All(context.Background(), db, query.Identity[users])
+	if err == nil || err.Error() != "query: no such column: nam" {
+		t.Errorf("unexpected error; got: %v", err)
+	}
+}
+
+func TestAllError(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"id > ?"`
+
+		Name sql.NullString `q:"nam"`
+	}
+	_, err := query.All(context.Background(), db, query.Identity[users], 1)
+	var qerr *query.Error
+	if !errors.As(err, &qerr) {
+		t.Fatalf("expected query error; got: %v", err)
+	}
+	const exp = "SELECT nam FROM users WHERE (id > ?)"
+	if qerr.Op != "query" || qerr.SQL != exp || qerr.Args != 1 || qerr.Field != "" {
+		t.Errorf("unexpected error context; got: %+v", qerr)
+	}
+	var serr sqlite3.Error
+	if !errors.As(err, &serr) || serr.Code != sqlite3.ErrError {
+		t.Errorf("expected driver error to be unwrapped; got: %v", err)
+	}
+}
+
+func TestAllScanErrorField(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"users.name = 'Bob'"`
+
+		Name      string
+		Addresses struct {
+			City int
+		} `q:"users.address_id = addresses.id"`
+	}
+	_, err := query.All(context.Background(), db, query.Identity[users])
+	var qerr *query.Error
+	if !errors.As(err, &qerr) || qerr.Op != "scan" || qerr.Field != "Addresses.City" {
+		t.Fatalf("expected scan error for Addresses.City; got: %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "scan: Addresses.City: ") {
+		t.Errorf("expected error message to name the field; got: %v", err)
+	}
+}
+
+func TestAllInvalidType(t *testing.T) {
+	type foo struct{}
+	type users struct {
+		Name foo `q:"name"`
+	}
+	_, err := query.All(context.Background(), db, query.Identity[users])
+	if err == nil || !strings.Contains(err.Error(), "unsupported Scan") {
+		t.Errorf("unexpected error; got: %v", err)
+	}
+}
+
+func TestCount(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name LIKE ?"`
+		query.OrderBy    `q:"name"`
+		query.Limit      `q:"1"`
+
+		Name string
+	}
+	count, err := query.Count[users](context.Background(), db, "J%")
+	if err != nil {
+		t.Fatalf("failed to count: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("unexpected count; got: %d", count)
+	}
+}
+
+func TestCountJoinMany(t *testing.T) {
+	type addresses struct {
+		City  string
+		Users []struct {
+			Name string
+		} `q:"users.address_id = addresses.id"`
+	}
+	count, err := query.Count[addresses](context.Background(), db)
+	if err != nil {
+		t.Fatalf("failed to count: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("unexpected count; got: %d", count)
+	}
+}
+
+func TestExists(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name = :name"`
+
+		Name string
+	}
+	for name, exp := range map[string]bool{"Bob": true, "Alice": false} {
+		exists, err := query.Exists[users](context.Background(), db, sql.Named("name", name))
+		if err != nil {
+			t.Fatalf("failed to check existence: %v", err)
+		}
+		if exists != exp {
+			t.Errorf("expected %q existence to be: %t; got: %t", name, exp, exists)
+		}
+	}
+}
+
+func TestColumn(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name LIKE ?"`
+		query.OrderBy    `q:"name"`
+
+		ID        int
+		Name      string
+		Addresses struct {
+			City string
+		} `q:"users.address_id = addresses.id"`
+	}
+	ids, err := query.Column[users, int](context.Background(), db, "ID", "J%")
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if diff := cmp.Diff([]int{2, 4, 1}, ids); diff != "" {
+		t.Error(diff)
+	}
+
+	cities, err := query.Column[users, string](context.Background(), db, "Addresses.City", "Bob")
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if diff := cmp.Diff([]string{"New York"}, cities); diff != "" {
+		t.Error(diff)
+	}
+
+	if _, err := query.Column[users, string](context.Background(), db, "Email"); err == nil {
+		t.Error("expected unknown field to return an error")
+	}
+
+	type addresses struct {
+		City  string
+		Users []struct {
+			Name string
+		} `q:"users.address_id = addresses.id"`
+	}
+	_, err = query.Column[addresses, string](context.Background(), db, "City")
+	if err == nil || !strings.Contains(err.Error(), "contains a many relationship") {
+		t.Errorf("expected many relationship to return an error; got: %v", err)
+	}
+}
+
+func TestScalar(t *testing.T) {
+	count, err := query.Scalar[int](context.Background(), db, "SELECT COUNT(*) FROM users WHERE name LIKE ?", "J%")
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("unexpected count; got: %d", count)
+	}
+
+	name, err := query.Scalar[string](context.Background(), db, "SELECT name FROM users WHERE id = :id", sql.Named("id", 5))
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if name != "Bob" {
+		t.Errorf("unexpected name; got: %q", name)
+	}
+
+	if _, err := query.Scalar[string](context.Background(), db, "SELECT id, name FROM users"); err == nil {
+		t.Error("expected multiple columns to return an error")
+	}
+	_, err = query.Scalar[string](context.Background(), db, "SELECT name FROM users WHERE id = 0")
+	if !errors.Is(err, query.ErrNotFound) {
+		t.Errorf("expected not found error; got: %v", err)
+	}
+}
+
+func TestRaw(t *testing.T) {
+	type users struct {
+		Name string
+		Rank int `q:"ROW_NUMBER() OVER (ORDER BY name DESC) AS position"`
+	}
+	results, err := query.Raw(context.Background(), db,
+		`SELECT 'ignored' AS note, ROW_NUMBER() OVER (ORDER BY name DESC) AS position, name FROM users WHERE name LIKE ?`,
+		query.Identity[users], "J%")
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	exp := []users{{"John", 1}, {"Joe", 2}, {"James", 3}}
+	if diff := cmp.Diff(exp, results); diff != "" {
+		t.Error(diff)
+	}
+
+	_, err = query.Raw(context.Background(), db, `SELECT name FROM users`, query.Identity[users], query.LimitTo(1))
+	if err == nil || err.Error() != "bind: modifiers cannot be applied to a raw SQL query" {
+		t.Errorf("unexpected error; got: %v", err)
+	}
+}
+
+func TestRawExpressionAlias(t *testing.T) {
+	type users struct {
+		ID    string `q:"CAST(id AS TEXT)"`
+		Name  string `q:"UPPER(name) AS \"upper\""`
+		Total int    `q:"(SELECT COUNT(*) FROM users AS u)"`
+	}
+	results, err := query.Raw(context.Background(), db,
+		`SELECT CAST(id AS TEXT), UPPER(name) AS upper, (SELECT COUNT(*) FROM users AS u) FROM users WHERE id = 1`,
+		query.Identity[users])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if diff := cmp.Diff([]users{{"1", "JOHN", 6}}, results); diff != "" {
+		t.Error(diff)
+	}
+
+	dbh := query.DB{DB: db, Options: &query.Options{Strict: true}}
+	type strict struct {
+		query.Table      `q:"users"`
+		query.Conditions `q:"id = 1"`
+
+		ID   string `q:"CAST(id AS TEXT)"`
+		Name string `q:"UPPER(name) AS upper"`
+	}
+	result, err := query.One(context.Background(), dbh, query.Identity[strict])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if result.ID != "1" || result.Name != "JOHN" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestRawJoinMany(t *testing.T) {
+	type addresses struct {
+		City    string
+		Country struct {
+			Name string `q:"country"`
+		} `q:"addresses.country_id = countries.id"`
+		Users []struct {
+			Name string
+		} `q:"users.address_id = addresses.id"`
+	}
+	results, err := query.Raw(context.Background(), db, `
+		SELECT addresses.id, addresses.city, countries.name AS country, users.id, users.name
+		FROM addresses
+		INNER JOIN countries ON addresses.country_id = countries.id
+		INNER JOIN users ON users.address_id = addresses.id
+		WHERE users.name IN (:names)
+		ORDER BY users.name`, query.Identity[addresses], sql.Named("names", []string{"Bob", "Joe"}))
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	addr := addresses{City: "New York"}
+	addr.Country.Name = "United States"
+	for _, name := range []string{"Bob", "Joe"} {
+		addr.Users = append(addr.Users, struct{ Name string }{name})
+	}
+	if diff := cmp.Diff([]addresses{addr}, results); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestOneConditions(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name = ?"`
+
+		ID   string         `q:"id"`
+		Name sql.NullString `q:"name"`
+	}
+	user, err := query.One(context.Background(), db, query.Identity[users], "Bob")
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	if user.Name.String != "Bob" {
+		t.Errorf("unexpected user; got: %v", user)
+	}
+}
+
+func TestOneNotFound(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"users.name = ?"`
+
+		Name string
+	}
+	type usersMany struct {
+		query.Table      `q:"users"`
+		query.Conditions `q:"users.name = ?"`
+
+		Name      string
+		Addresses []struct {
+			City string
+		} `q:"users.address_id = addresses.id"`
+	}
+	transformed := false
+	_, err := query.One(context.Background(), db, func(u users) string {
+		transformed = true
+		return u.Name
+	}, "Nobody")
+	if !errors.Is(err, query.ErrNotFound) || !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected not found error; got: %v", err)
+	}
+	_, err = query.One(context.Background(), db, func(u usersMany) string {
+		transformed = true
+		return u.Name
+	}, "Nobody")
+	if !errors.Is(err, query.ErrNotFound) {
+		t.Errorf("expected not found error for many relationship; got: %v", err)
+	}
+	_, err = query.One(context.Background(), db, func(u users) string {
+		transformed = true
+		return u.Name
+	})
+	if err == nil {
+		t.Error("expected missing argument error")
+	}
+	if transformed {
+		t.Error("expected transform not to be called when an error is returned")
+	}
+}
+
+func TestMaybe(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name = ?"`
+
+		Name string
+	}
+	name, ok, err := query.Maybe(context.Background(), db, func(u users) string { return u.Name }, "Bob")
+	if err != nil || !ok || name != "Bob" {
+		t.Errorf("expected Bob; got: %q, %v, %v", name, ok, err)
+	}
+	name, ok, err = query.Maybe(context.Background(), db, func(u users) string { return u.Name }, "Nobody")
+	if err != nil || ok || name != "" {
+		t.Errorf("expected no result; got: %q, %v, %v", name, ok, err)
+	}
+}
+
+func TestAllErr(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name IS NOT NULL"`
+		query.OrderBy    `q:"name"`
+
+		Name string
+	}
+	errShort := errors.New("name too short")
+	transform := func(u users) (string, error) {
+		if len(u.Name) < 4 {
+			return "", errShort
+		}
+		return u.Name, nil
+	}
+	_, err := query.AllErr(context.Background(), db, transform)
+	var terr *query.TransformError
+	if !errors.As(err, &terr) || terr.Row != 0 || !errors.Is(err, errShort) {
+		t.Errorf("expected transform error for row 0; got: %v", err)
+	}
+
+	results, err := query.AllErr(context.Background(), db, transform, query.Where("LENGTH(name) > 3"))
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if diff := cmp.Diff([]string{"Gary", "James", "John"}, results); diff != "" {
+		t.Error(diff)
+	}
+
+	_, err = query.AllErr(context.Background(), db, func(u users) (string, error) {
+		if u.Name == "James" {
+			return "", errShort
+		}
+		return u.Name, nil
+	})
+	if !errors.As(err, &terr) || terr.Row != 2 || err.Error() != "transform: row 2: name too short" {
+		t.Errorf("expected transform error for row 2; got: %v", err)
+	}
+}
+
+func TestOneContext(t *testing.T) {
+	type key struct{}
+	type users struct {
+		query.Conditions `q:"name = ?"`
+
+		Name string
+	}
+	ctx := context.WithValue(context.Background(), key{}, "Mr. ")
+	name, err := query.OneContext(ctx, db, func(ctx context.Context, u users) (string, error) {
+		return ctx.Value(key{}).(string) + u.Name, nil
+	}, "Bob")
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if name != "Mr. Bob" {
+		t.Errorf("expected Mr. Bob; got: %q", name)
+	}
+
+	errInvalid := errors.New("invalid")
+	_, err = query.OneErr(context.Background(), db, func(u users) (string, error) {
+		return u.Name, errInvalid
+	}, "Bob")
+	if !errors.Is(err, errInvalid) {
+		t.Errorf("expected transform error; got: %v", err)
+	}
+}
+
+func TestMap(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name IS NOT NULL"`
+
+		ID   int
+		Name string
+	}
+	results, err := query.Map(context.Background(), db, func(name string) int { return len(name) },
+		func(u users) string { return u.Name })
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	exp := map[int]string{3: "Bob", 4: "Gary", 5: "James"}
+	if diff := cmp.Diff(exp, results); diff != "" {
+		t.Error(diff)
+	}
+
+	byID, err := query.MapUnique(context.Background(), db, func(u users) int { return u.ID }, query.Identity[users])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if len(byID) != 5 || byID[1].Name != "John" {
+		t.Errorf("unexpected results: %v", byID)
+	}
+
+	_, err = query.MapUnique(context.Background(), db, func(name string) int { return len(name) },
+		func(u users) string { return u.Name }, query.Order("id"))
+	var derr *query.DuplicateKeyError
+	if !errors.As(err, &derr) || derr.Key != 4 || derr.Row != 2 {
+		t.Errorf("expected duplicate key error; got: %v", err)
+	}
+}
+
+func TestGroupInto(t *testing.T) {
+	type users struct {
+		query.OrderBy `q:"users.id"`
+
+		Name      sql.NullString
+		Addresses struct {
+			City sql.NullString
+		} `q:"users.address_id = addresses.id"`
+	}
+	results, err := query.GroupInto(context.Background(), db, func(u users) string { return u.Addresses.City.String },
+		func(u users) users { return u })
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	var names []string
+	for _, u := range results["New York"] {
+		names = append(names, u.Name.String)
+	}
+	if diff := cmp.Diff([]string{"John", "James", "Gary", "Joe", "Bob"}, names); diff != "" {
+		t.Error(diff)
+	}
+	if len(results) != 1 {
+		t.Errorf("expected a single group; got: %d", len(results))
+	}
+}
+
+func TestGroupIntoMany(t *testing.T) {
+	type addresses struct {
+		query.OrderBy `q:"addresses.id, users.id"`
+
+		City  string
+		Users []struct {
+			Name string
+		} `q:"users.address_id = addresses.id"`
+	}
+	results, err := query.GroupInto(context.Background(), db, func(n int) int { return n },
+		func(a addresses) int { return len(a.Users) })
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if diff := cmp.Diff(map[int][]int{5: {5}}, results); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestFold(t *testing.T) {
+	var logged string
+	dbh := query.DB{DB: db, Options: &query.Options{Logger: func(query string, args []any) { logged = query }}}
+	type users struct {
+		query.Conditions `q:"name LIKE ?"`
+
+		Name string
+	}
+	lengths, err := query.Fold(context.Background(), dbh, map[int]int{}, func(acc map[int]int, u users) map[int]int {
+		acc[len(u.Name)]++
+		return acc
+	}, "J%")
+	if err != nil {
+		t.Fatalf("failed to fold: %v", err)
+	}
+	if diff := cmp.Diff(map[int]int{3: 1, 4: 1, 5: 1}, lengths); diff != "" {
+		t.Error(diff)
+	}
+	const exp = "SELECT users.name FROM users WHERE (name LIKE ?)"
+	if logged != exp {
+		t.Errorf("expected query to be: %q; got: %q", exp, logged)
+	}
+
+	total, err := query.Fold(context.Background(), db, 10, func(acc int, u users) int { return acc + 1 }, "X%")
+	if err != nil || total != 10 {
+		t.Errorf("expected initial value; got: %d, %v", total, err)
+	}
+}
+
+func TestFoldPartial(t *testing.T) {
+	type users struct {
+		query.OrderBy `q:"users.id"`
+
+		Name string
+	}
+	seen, err := query.Fold(context.Background(), db, map[string]bool{}, func(acc map[string]bool, u users) map[string]bool {
+		acc[u.Name] = true
+		return acc
+	})
+	var qerr *query.Error
+	if !errors.As(err, &qerr) || qerr.Op != "scan" {
+		t.Fatalf("expected scan error; got: %v", err)
+	}
+	exp := map[string]bool{"John": true, "James": true, "Gary": true, "Joe": true, "Bob": true}
+	if diff := cmp.Diff(exp, seen); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestOneCount(t *testing.T) {
+	type users struct {
+		Count int `q:"COUNT(*)"`
+	}
+	count, err := query.One(context.Background(), db, func(u users) int { return u.Count })
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	if count != 6 {
+		t.Errorf("unexpected count; got: %v", count)
+	}
+}
+
+func TestOneOptional(t *testing.T) {
+	type users struct {
+		query.OrderBy  `q:"name DESC"`
+		query.Optional `q:"name = :name"`
+
+		Name sql.NullString
+	}
+	result, err := query.One(context.Background(), db, query.Identity[users], sql.Named("name", "Bob"))
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if result.Name.String != "Bob" {
+		t.Errorf("unexpected result; got: %v", result)
+	}
+
+	result, err = query.One(context.Background(), db, query.Identity[users], sql.Named("name", nil))
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if result.Name.String != "John" {
+		t.Errorf("unexpected result; got: %v", result)
+	}
+}
+
+func TestOneNamed(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"users.id = :user_id"`
+
+		Name sql.NullString
+	}
+	type params struct {
+		UserID int `q:"user_id"`
+	}
+	result, err := query.OneNamed(context.Background(), db, query.Identity[users], &params{UserID: 1})
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if result.Name.String != "John" {
+		t.Errorf("unexpected result; got: %v", result)
+	}
+}
+
+func TestOneWith(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"users.id = :user_id"`
+
+		Name sql.NullString
+	}
+	type params struct {
+		UserID int
+	}
+	result, err := query.OneWith(context.Background(), db, query.Identity[users], params{UserID: 5})
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if result.Name.String != "Bob" {
+		t.Errorf("unexpected result; got: %v", result)
+	}
+}
+
+func TestOneModifiers(t *testing.T) {
+	type users struct {
+		Name sql.NullString
+	}
+	result, err := query.One(context.Background(), db, query.Identity[users], query.Where("name = $1", "Gary"))
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if result.Name.String != "Gary" {
+		t.Errorf("unexpected result; got: %v", result)
+	}
+}
+
+func TestOneTableName(t *testing.T) {
+	type userTable struct {
+		query.Table      `q:"users"`
+		query.Conditions `q:"name = ?"`
+
+		Name sql.NullString `q:"name"`
+	}
+	result, err := query.One(context.Background(), db, query.Identity[userTable], "Bob")
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	if result.Name.String != "Bob" {
+		t.Errorf("unexpected result; got: %v", result)
+	}
+}
+
+func TestOneJoin(t *testing.T) {
+	type users struct {
+		query.OrderBy `q:"name DESC"`
+
+		Name      string `q:"name"`
+		Addresses struct {
+			City string `q:"city"`
+		} `q:"users.address_id = addresses.id"`
+	}
+	result, err := query.One(context.Background(), db, query.Identity[users])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	exp := users{Name: "John", Addresses: struct {
+		City string `q:"city"`
+	}{"New York"}}
+	if diff := cmp.Diff(exp, result); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestOneNestedJoin(t *testing.T) {
 	type users struct {
 		query.OrderBy `q:"users.name DESC"`
 
@@ -743,6 +2497,122 @@ func TestOneLeftJoin(t *testing.T) {
 	}
 }
 
+func TestAllPointerColumns(t *testing.T) {
+	type users struct {
+		query.OrderBy `q:"users.id"`
+
+		Name      *string
+		AddressID *int
+		CreatedAt *time.Time `q:"NULL"`
+	}
+	results, err := query.All(context.Background(), db, query.Identity[users])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if len(results) != 6 {
+		t.Fatalf("expected 6 results, got %d", len(results))
+	}
+
+	first, last := results[0], results[5]
+	if first.Name == nil || *first.Name != "John" || first.AddressID == nil || *first.AddressID != 2 {
+		t.Errorf("expected John at address 2, got %v at %v", first.Name, first.AddressID)
+	}
+	if results[1].Name == nil || *results[1].Name != "James" {
+		t.Errorf("expected James, got %v", results[1].Name)
+	}
+	if last.Name != nil || last.AddressID != nil || last.CreatedAt != nil {
+		t.Errorf("expected nil pointers for null columns, got %v", last)
+	}
+}
+
+func TestAllPointerJoin(t *testing.T) {
+	type country struct {
+		query.LeftJoin
+
+		Name string
+	}
+	type users struct {
+		query.OrderBy `q:"users.id"`
+
+		Name      sql.NullString
+		Addresses *struct {
+			query.LeftJoin
+
+			City      string
+			Countries *country `q:"addresses.country_id = countries.id"`
+		} `q:"users.address_id = addresses.id"`
+	}
+	results, err := query.All(context.Background(), db, query.Identity[users])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if len(results) != 6 {
+		t.Fatalf("expected 6 results, got %d", len(results))
+	}
+
+	for _, r := range results[:5] {
+		if r.Addresses == nil {
+			t.Fatalf("expected address for %s", r.Name.String)
+		}
+		if r.Addresses.City != "New York" || r.Addresses.Countries == nil || r.Addresses.Countries.Name != "United States" {
+			t.Errorf("unexpected address for %s: %+v", r.Name.String, r.Addresses)
+		}
+	}
+	if results[0].Addresses == results[1].Addresses {
+		t.Error("expected each result to hold its own address")
+	}
+	if results[5].Addresses != nil {
+		t.Errorf("expected nil address, got %+v", results[5].Addresses)
+	}
+}
+
+func TestOnePointerJoin(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"users.name IS NULL"`
+
+		ID        int
+		Addresses *struct {
+			query.LeftJoin
+
+			City string
+		} `q:"users.address_id = addresses.id"`
+	}
+	result, err := query.One(context.Background(), db, query.Identity[users])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if result.Addresses != nil {
+		t.Errorf("expected nil address, got %+v", result.Addresses)
+	}
+}
+
+func TestAllPointerJoinMany(t *testing.T) {
+	type addresses struct {
+		query.OrderBy `q:"addresses.id, users.id"`
+
+		City      string
+		Countries *struct {
+			Name string
+		} `q:"addresses.country_id = countries.id"`
+		Users []struct {
+			Name string
+		} `q:"users.address_id = addresses.id"`
+	}
+	results, err := query.All(context.Background(), db, query.Identity[addresses])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if results[0].Countries == nil || results[0].Countries.Name != "United States" {
+		t.Errorf("unexpected country: %+v", results[0].Countries)
+	}
+	if len(results[0].Users) != 5 {
+		t.Errorf("expected 5 users, got %d", len(results[0].Users))
+	}
+}
+
 func TestOneJoinConditions(t *testing.T) {
 	type users struct {
 		query.OrderBy `q:"name DESC"`
@@ -789,6 +2659,30 @@ func TestOneJoinMany(t *testing.T) {
 	}
 }
 
+func TestOneJoinManyTransformFirst(t *testing.T) {
+	type users struct {
+		query.OrderBy `q:"users.id"`
+
+		Name      string
+		Addresses []struct {
+			City string
+		} `q:"users.address_id = addresses.id"`
+	}
+	calls := 0
+	result, err := query.OneErr(context.Background(), db, func(u users) (string, error) {
+		if calls++; calls > 1 {
+			return "", errors.New("transformed a discarded result")
+		}
+		return u.Name, nil
+	})
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if result != "John" || calls != 1 {
+		t.Errorf("expected John to be transformed once; got: %q after %d calls", result, calls)
+	}
+}
+
 func TestOneJoinManySharedRow(t *testing.T) {
 	type users struct {
 		Name      string
@@ -956,11 +2850,43 @@ func TestOneInvalidField(t *testing.T) {
 		Name sql.NullString `q:"nam"`
 	}
 	_, err := query.One(context.Background(), db, query.Identity[users])
-	if err == nil || err.Error() != "no such column: nam" {
+	if err == nil || err.Error() != "query: no such column: nam" {
 		t.Errorf("unexpected error; got: %v", err)
 	}
 }
 
+func TestOneScanErrorField(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"users.name = 'Bob'"`
+
+		Name      string
+		Addresses struct {
+			City int
+		} `q:"users.address_id = addresses.id"`
+	}
+	for _, strict := range []bool{false, true} {
+		dbh := query.DB{DB: db, Options: &query.Options{Strict: strict}}
+		_, err := query.One(context.Background(), dbh, query.Identity[users])
+		var qerr *query.Error
+		if !errors.As(err, &qerr) || qerr.Op != "scan" || qerr.Field != "Addresses.City" {
+			t.Errorf("expected scan error for Addresses.City with strict %t; got: %v", strict, err)
+		}
+	}
+}
+
+func TestOneScanErrorNoField(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name = 'Bob'"`
+
+		Name string `q:"id, name"`
+	}
+	_, err := query.One(context.Background(), db, query.Identity[users])
+	var qerr *query.Error
+	if !errors.As(err, &qerr) || qerr.Op != "scan" || qerr.Field != "" {
+		t.Errorf("expected scan error without a field; got: %v", err)
+	}
+}
+
 func TestOneInvalidType(t *testing.T) {
 	type foo struct{}
 	type users struct {
@@ -972,6 +2898,222 @@ func TestOneInvalidType(t *testing.T) {
 	}
 }
 
+func TestValidate(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name = $1 OR id = $2"`
+		query.Limit      `q:"10"`
+
+		ID        int
+		Name      sql.NullString
+		CreatedAt time.Time  `q:"NULL"`
+		DeletedAt *time.Time `q:"NULL"`
+		Nickname  *string    `q:"NULL"`
+		Addresses []struct {
+			City      string
+			Countries *struct{ Name string } `q:"addresses.country_id = countries.id"`
+		} `q:"users.address_id = addresses.id"`
+	}
+	if err := query.Validate[users](nil); err != nil {
+		t.Errorf("expected query to be valid; got: %v", err)
+	}
+}
+
+func TestValidateProblems(t *testing.T) {
+	type base struct {
+		query.Table `q:"people"`
+
+		ID int
+	}
+	type tags map[string]string
+	type users struct {
+		query.Table `q:"users"`
+		base
+
+		Name      string
+		Tags      tags
+		secret    string
+		Limit     query.Limit `q:"10"`
+		Limit2    query.Limit `q:"20"`
+		Addresses []struct {
+			City      string
+			Countries struct{ Name string }
+		}
+		Location struct {
+			City string
+		}
+		Country *struct {
+			Name string
+		}
+	}
+	err := query.Validate[users](nil)
+	var verr *query.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected validation error; got: %v", err)
+	}
+
+	var problems []string
+	for _, err := range verr.Errors {
+		var serr *query.StructError
+		if !errors.As(err, &serr) {
+			t.Fatalf("expected struct error; got: %v", err)
+		}
+		problems = append(problems, serr.Path)
+	}
+	exp := []string{"base", "Tags", "secret", "Limit2", "Addresses", "Location", "Country"}
+	if diff := cmp.Diff(exp, problems); diff != "" {
+		t.Error(diff)
+	}
+	for _, target := range []error{
+		query.ErrTableConflict, query.ErrUnsupportedType, query.ErrUnexported, query.ErrDuplicateMarker,
+		query.ErrJoinTag,
+	} {
+		if !errors.Is(err, target) {
+			t.Errorf("expected error to include %q", target)
+		}
+	}
+}
+
+func TestValidatePlaceholders(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name = $1 OR id = $3"`
+		query.Limit      `q:"?"`
+
+		Name string
+	}
+	err := query.Validate[users](nil)
+	const exp = "query: query_test.users: invalid placeholders: query mixes ? and $n placeholders\n" +
+		"query: query_test.users: invalid placeholders: $2 is not referenced by the query"
+	if err == nil || err.Error() != exp {
+		t.Errorf("expected error %q; got: %v", exp, err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	var logged string
+	dbh := query.DB{DB: db, Options: &query.Options{Logger: func(query string, args []any) { logged = query }}}
+	type users struct {
+		query.Conditions `q:"users.id = ? OR users.id IN (?)"`
+		query.Optional   `q:"name = :name"`
+		query.Limit      `q:"?"`
+
+		ID        int
+		Name      sql.NullString
+		Addresses []struct {
+			City sql.NullString
+		} `q:"users.address_id = addresses.id"`
+	}
+	if err := query.Check[users](context.Background(), dbh); err != nil {
+		t.Fatalf("expected query to match the schema; got: %v", err)
+	}
+
+	const exp = "SELECT * FROM (SELECT users.id AS c0, users.id AS c1, users.name AS c2, addresses.id AS c3," +
+		" addresses.city AS c4 FROM users INNER JOIN addresses ON users.address_id = addresses.id" +
+		" WHERE (users.id = ? OR users.id IN (?)) AND (name = ?) LIMIT ?) AS checked WHERE (1 = 0)"
+	if logged != exp {
+		t.Errorf("expected query to be: %q; got: %q", exp, logged)
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	type missingColumn struct {
+		query.Table `q:"users"`
+
+		Email string
+	}
+	err := query.Check[missingColumn](context.Background(), db)
+	if err == nil || !strings.Contains(err.Error(), "no such column: users.email") {
+		t.Errorf("expected missing column error; got: %v", err)
+	}
+
+	type missingTable struct {
+		ID int
+	}
+	err = query.Check[missingTable](context.Background(), db)
+	if err == nil || !strings.Contains(err.Error(), "no such table: missing_table") {
+		t.Errorf("expected missing table error; got: %v", err)
+	}
+
+	type expanded struct {
+		query.Table `q:"users"`
+
+		ID   int `q:"id, address_id"`
+		Name string
+	}
+	err = query.Check[expanded](context.Background(), db)
+	if err == nil || !strings.Contains(err.Error(), "for field ID") {
+		t.Errorf("expected column mismatch error; got: %v", err)
+	}
+}
+
+// reusingScanner is a [sql.Scanner] which reuses its buffer on each scan.
+type reusingScanner struct{ buf []byte }
+
+func (s *reusingScanner) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		s.buf = append(s.buf[:0], v...)
+	case []byte:
+		s.buf = append(s.buf[:0], v...)
+	default:
+		s.buf = s.buf[:0]
+	}
+	return nil
+}
+
+func TestAllRowsNotShared(t *testing.T) {
+	type users struct {
+		query.Conditions `q:"name IS NOT NULL"`
+		query.OrderBy    `q:"id"`
+
+		Name  reusingScanner  `q:"name"`
+		Bytes []byte          `q:"name"`
+		Raw   sql.RawBytes    `q:"name"`
+		JSON  json.RawMessage `q:"'\"' || name || '\"'"`
+	}
+	results, err := query.All(context.Background(), db, query.Identity[users])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	var names []string
+	for _, u := range results {
+		names = append(names, string(u.Name.buf)+"/"+string(u.Bytes)+"/"+string(u.Raw)+"/"+string(u.JSON))
+	}
+	exp := []string{
+		`John/John/John/"John"`,
+		`James/James/James/"James"`,
+		`Gary/Gary/Gary/"Gary"`,
+		`Joe/Joe/Joe/"Joe"`,
+		`Bob/Bob/Bob/"Bob"`,
+	}
+	if diff := cmp.Diff(exp, names); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAllManyRowsNotShared(t *testing.T) {
+	type addresses struct {
+		query.OrderBy `q:"users.id"`
+
+		City  string
+		Users []struct {
+			Name reusingScanner `q:"name"`
+		} `q:"users.address_id = addresses.id"`
+	}
+	results, err := query.All(context.Background(), db, query.Identity[addresses])
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	var names []string
+	for _, a := range results {
+		for _, u := range a.Users {
+			names = append(names, string(u.Name.buf))
+		}
+	}
+	if diff := cmp.Diff([]string{"John", "James", "Gary", "Joe", "Bob"}, names); diff != "" {
+		t.Error(diff)
+	}
+}
+
 var setupQueries = []string{
 	`CREATE TABLE countries (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)`,
 	`CREATE TABLE addresses (id INTEGER PRIMARY KEY AUTOINCREMENT, city TEXT, country_id INTEGER REFERENCES countries(id))`,
@@ -0,0 +1,79 @@
+package query
+
+import (
+	"context"
+	"fmt"
+)
+
+// Raw is like [All] but executes the supplied SQL query rather than a query generated from the Source type. This
+// allows queries that cannot be described by a query struct, such as those using window functions or vendor specific
+// syntax, to be scanned into the same query structs. Only the columns of the Source type are used; the table,
+// conditions and other query properties are ignored.
+//
+// The result columns are mapped to the fields of the Source type by name, using the "q" struct tag if provided,
+// otherwise the name produced by the [Namer]. The alias of a column expression (e.g. "COUNT(*) AS count") is used as
+// its name. Result columns with the same name are mapped in the order the fields are defined, including those of
+// nested join structs. Many relationships require the identity column of each table to be selected before the table's
+// columns. Result columns that do not match a field are discarded. Example:
+//
+//	type users struct {
+//		Name string
+//		Rank int `q:"rank"`
+//	}
+//	results, _ := query.Raw(ctx, db, `SELECT name, RANK() OVER (ORDER BY score DESC) AS rank FROM users`,
+//		query.Identity[users])
+//
+// Named arguments and slices bound to IN lists are resolved as they are for [All]. As the query is not generated,
+// [Modifier] arguments are rejected and the [Options] Rewrite function is not applied.
+func Raw[Source, Destination any](ctx context.Context, tx Transaction, query string, transform Transform[Source, Destination], args ...any) ([]Destination, error) {
+	var results []Source
+	stmt, complete, err := preparer{namer: nameWith(tx)}.prepareSet(&results)
+	if err != nil {
+		return nil, err
+	}
+
+	query, args, err = bindRaw(tx, query, args)
+	if err != nil {
+		return nil, fail("bind", "", nil, err)
+	}
+	log(tx, query, args)
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fail("query", query, args, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fail("columns", query, args, err)
+	}
+	bindings, fields := stmt.match(columns)
+	err = scan(rows, query, args, bindings, fields, func() error {
+		complete()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	transformed := make([]Destination, len(results))
+	for i, result := range results {
+		transformed[i] = transform(result)
+	}
+	return transformed, nil
+}
+
+// bindRaw returns the supplied SQL query and the arguments to send with it. Named parameters are resolved to ordinal
+// placeholders using the placeholder style of the [Transaction], and slices bound to IN lists are expanded into a
+// placeholder for each element. An error is returned if a [Modifier] is supplied, as a raw query cannot be modified.
+func bindRaw(tx Transaction, query string, args []any) (string, []any, error) {
+	for _, arg := range args {
+		if _, ok := arg.(Modifier); ok {
+			return "", nil, fmt.Errorf("modifiers cannot be applied to a raw SQL query")
+		}
+	}
+	if named := namedArgs(args); named != nil || hasList(args) {
+		return resolve(query, bindWith(tx), args, named)
+	}
+	return query, args, nil
+}
@@ -0,0 +1,125 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Column returns the values of a single field of the Source type for each result of the query. The field is
+// identified by its name, using dots to separate the names of nested join structs (e.g. "Addresses.City"). Only the
+// column of the field is selected; the table, joins and other properties of the Source type are unchanged. A Source
+// type containing a many relationship is rejected, as its rows would repeat the values of the parent. Example:
+//
+//	type users struct {
+//		query.Conditions `q:"name LIKE ?"`
+//
+//		ID   int
+//		Name string
+//	}
+//	// Query: SELECT users.id FROM users WHERE (name LIKE ?)
+//	ids, _ := query.Column[users, int](ctx, db, "ID", "J%")
+func Column[Source, T any](ctx context.Context, tx Transaction, field string, args ...any) ([]T, error) {
+	var src Source
+	if hasMany(reflect.TypeOf(src)) {
+		return nil, fmt.Errorf("column: %T contains a many relationship", src)
+	}
+	stmt, err := plan[Source](nameWith(tx))
+	if err != nil {
+		return nil, err
+	}
+	index, err := fieldIndex(reflect.TypeOf(src), field)
+	if err != nil {
+		return nil, fmt.Errorf("column: %w", err)
+	}
+	if selected := stmt.only(index); selected.columnCount() != 1 {
+		return nil, fmt.Errorf("column: %T.%s is not a column", src, field)
+	}
+
+	query, args, err := bind(&stmt, tx, args, func(stmt statement) statement {
+		return stmt.only(index)
+	})
+	if err != nil {
+		return nil, fail("bind", "", nil, err)
+	}
+	log(tx, query, args)
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fail("query", query, args, err)
+	}
+	defer rows.Close()
+
+	var values []T
+	for rows.Next() {
+		var value T
+		if err := rows.Scan(&value); err != nil {
+			return nil, &Error{Op: "scan", SQL: query, Args: len(args), Field: field, Err: err}
+		}
+		values = append(values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fail("close", query, args, err)
+	}
+	return values, nil
+}
+
+// Scalar returns the value of the first result of the supplied SQL query, which must select a single column. This
+// avoids the need for a query struct and a transform function to unwrap the value. Example:
+//
+//	count, _ := query.Scalar[int](ctx, db, "SELECT COUNT(*) FROM users WHERE name LIKE ?", "J%")
+//
+// Arguments are bound as they are for [Raw]. Like [One], [ErrNotFound] is returned if the query does not return any
+// results.
+func Scalar[T any](ctx context.Context, tx Transaction, query string, args ...any) (T, error) {
+	var value T
+	query, args, err := bindRaw(tx, query, args)
+	if err != nil {
+		return value, fail("bind", "", nil, err)
+	}
+	log(tx, query, args)
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return value, fail("query", query, args, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return value, fail("columns", query, args, err)
+	}
+	if len(columns) != 1 {
+		return value, fail("scalar", query, args, fmt.Errorf("query selects %d columns", len(columns)))
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return value, fail("close", query, args, err)
+		}
+		return value, ErrNotFound
+	}
+	if err := rows.Scan(&value); err != nil {
+		return value, &Error{Op: "scan", SQL: query, Args: len(args), Field: columns[0], Err: err}
+	}
+	if err := rows.Close(); err != nil {
+		return value, fail("close", query, args, err)
+	}
+	return value, nil
+}
+
+// fieldIndex returns the index sequence of the named field of the supplied struct type. Names of nested fields are
+// separated by dots.
+func fieldIndex(typ reflect.Type, name string) ([]int, error) {
+	var index []int
+	for _, part := range strings.Split(name, ".") {
+		if typ.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%s is not a struct field of %s", name, typ)
+		}
+		fld, ok := typ.FieldByName(part)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a field of %s", part, typ)
+		}
+		index = append(index, fld.Index...)
+		typ = fld.Type
+	}
+	return index, nil
+}
@@ -0,0 +1,86 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SortError is returned by [Sort] when a sort key does not identify a column of the query.
+type SortError struct {
+	Key string
+}
+
+// Error returns the error message.
+func (e *SortError) Error() string {
+	return fmt.Sprintf("query: unknown sort key %q", e.Key)
+}
+
+// Sort returns a [Modifier] which orders the query by the comma separated keys of the supplied spec. This is intended
+// for sorting using untrusted input, such as the query parameters of an HTTP request. Each key must identify a column
+// of the Source type, either by its "q" struct tag or by the name produced by the [Namer]. A key prefixed with "-"
+// is sorted in descending order. The ordering replaces the ordering defined by the query struct, which remains in
+// effect when the spec is empty. A *[SortError] is returned if a key does not identify a column. The default namer
+// is used if the namer is nil. Example:
+//
+//	type users struct {
+//		ID        int
+//		Name      string
+//		CreatedAt time.Time
+//	}
+//	sort, err := query.Sort[users](nil, "-name,created_at")
+//	// Query: SELECT ... FROM users ORDER BY users.name DESC, users.created_at
+//	results, _ := query.All(ctx, db, query.Identity[users], sort)
+func Sort[Source any](namer Namer, spec string) (Modifier, error) {
+	if namer == nil {
+		namer = defaultNamer
+	}
+	stmt, err := plan[Source](namer)
+	if err != nil {
+		return Modifier{}, err
+	}
+
+	var order []string
+	for _, key := range parseSort(spec) {
+		expr, _, ok := stmt.column(key.name)
+		if !ok {
+			return Modifier{}, &SortError{Key: key.name}
+		}
+		if key.desc {
+			expr += " DESC"
+		}
+		order = append(order, expr)
+	}
+	if len(order) == 0 {
+		return Modifier{}, nil
+	}
+	return ReplaceOrder(strings.Join(order, ", ")), nil
+}
+
+// sortKey identifies a key of a sort spec.
+type sortKey struct {
+	name string
+	desc bool
+}
+
+// parseSort returns the keys of the supplied comma separated sort spec, as accepted by [Sort] and [Keyset]. A key
+// prefixed with "-" is sorted in descending order and a key prefixed with "+" is sorted in ascending order. Empty
+// keys are skipped.
+func parseSort(spec string) []sortKey {
+	var keys []sortKey
+	for _, name := range strings.Split(spec, ",") {
+		var key sortKey
+		name = strings.TrimSpace(name)
+		switch {
+		case strings.HasPrefix(name, "-"):
+			key.name, key.desc = name[1:], true
+		case strings.HasPrefix(name, "+"):
+			key.name = name[1:]
+		default:
+			key.name = name
+		}
+		if key.name != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
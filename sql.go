@@ -1,15 +1,92 @@
 package query
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 )
 
 // column identifies a select column. It contains the column name and a useTable flag. If useTable is set, the
 // query builder will specify that the column name is associated with the current table. This prevents overlapping
-// column names in joins.
+// column names in joins. The field identifies the index sequence of the struct field the column is scanned into, if
+// the field is addressable from the query struct, and path holds the dot separated names of the field. The dest
+// holds the destination suitable for use by [sql.Rows.Scan]. If an alias is assigned, the column is selected using
+// the alias.
 type column struct {
 	name     string
 	useTable bool
+	field    []int
+	path     string
+	dest     any
+	alias    string
+}
+
+// expr returns the SQL expression used to reference the column from the supplied table.
+func (c column) expr(table string) string {
+	if c.useTable {
+		return table + "." + c.name
+	}
+	return c.name
+}
+
+// key returns the name of the column within a result set. This is the alias of the column expression, if provided,
+// otherwise the unqualified column name.
+func (c column) key() string {
+	if c.alias != "" {
+		return c.alias
+	}
+	if alias, ok := c.definedAlias(); ok {
+		return alias
+	}
+	if isReference(c.name) {
+		return c.name[strings.LastIndexByte(c.name, '.')+1:]
+	}
+	return c.name
+}
+
+// definedAlias returns the alias defined by the column expression (e.g. "COUNT(*) AS count"), if any. Only a trailing
+// "AS" followed by an identifier, outside of parentheses and quotes, defines an alias; the "AS" of an expression such
+// as "CAST(id AS TEXT)" does not. The quotes of a quoted alias are removed.
+func (c column) definedAlias() (string, bool) {
+	as := -1
+	depth := 0
+	for i := 0; i < len(c.name); i++ {
+		switch ch := c.name[i]; ch {
+		case '\'', '"', '`':
+			for i++; i < len(c.name) && c.name[i] != ch; i++ {
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ' ', '\t', '\n':
+			if depth == 0 && i+3 < len(c.name) && strings.EqualFold(c.name[i+1:i+3], "AS") && isSpace(c.name[i+3]) {
+				as = i
+			}
+		}
+	}
+	if as < 0 {
+		return "", false
+	}
+
+	alias := strings.TrimSpace(c.name[as+4:])
+	if n := len(alias); n >= 2 && (alias[0] == '"' || alias[0] == '`') && alias[n-1] == alias[0] {
+		return alias[1 : n-1], true
+	}
+	if alias == "" {
+		return "", false
+	}
+	for i := 0; i < len(alias); i++ {
+		if !isIdent(alias[i], i == 0) {
+			return "", false
+		}
+	}
+	return alias, true
+}
+
+// isSpace returns true if the supplied character is whitespace.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
 }
 
 // join identifies a join type that specifies how tables should be joined.
@@ -22,13 +99,71 @@ const (
 	joinLeft
 )
 
+// condition identifies an expression of the WHERE section of a query. A condition is either a single expression or a
+// group of branch conditions joined together using the group operator. An optional expression is only included in
+// the query when the named arguments it references are provided.
+type condition struct {
+	expr     string
+	optional bool
+	operator string
+	branches []condition
+}
+
+// Condition group operators.
+const (
+	operatorAnd = " AND "
+	operatorOr  = " OR "
+)
+
+// walk calls the supplied function with each expression of the condition.
+func (c *condition) walk(fn func(expr *string)) {
+	if c.operator == "" {
+		fn(&c.expr)
+		return
+	}
+	for i := range c.branches {
+		c.branches[i].walk(fn)
+	}
+}
+
+// empty returns true if the condition does not produce an expression.
+func (c *condition) empty() bool {
+	return c.operator != "" && len(c.branches) == 0
+}
+
+// prune returns the condition without the optional expressions that reference named arguments which are not
+// present. False is returned if the condition is to be removed entirely.
+func (c condition) prune(present func(name string) bool) (condition, bool) {
+	if c.operator == "" {
+		if !c.optional {
+			return c, true
+		}
+		for _, p := range placeholders(c.expr) {
+			if p.name != "" && !present(p.name) {
+				return c, false
+			}
+		}
+		return c, true
+	}
+
+	branches := make([]condition, 0, len(c.branches))
+	for _, branch := range c.branches {
+		if branch, ok := branch.prune(present); ok {
+			branches = append(branches, branch)
+		}
+	}
+	c.branches = branches
+	return c, len(branches) > 0
+}
+
 // statement represents the properties of a query. It is used to facilitate the generation of a SQL query.
 type statement struct {
 	columns    []column
 	table      string
-	conditions []string
+	conditions []condition
 	order      []string
 	group      []string
+	having     []condition
 	limit      string
 	offset     string
 
@@ -40,137 +175,338 @@ type statement struct {
 
 // SQL returns the query specified by the statement structure.
 func (s *statement) SQL() string {
-	var query strings.Builder
-	query.WriteString("SELECT ")
-	s.writeColumns(&query, 0)
-	query.WriteString(" FROM ")
-	query.WriteString(s.table)
+	st := s.export()
+	return st.SQL()
+}
 
-	for _, join := range s.joins {
-		join.writeJoin(&query)
+// bindings returns the scan destinations of the statement columns, including those of its joins, in the order that
+// the columns are written by [statement.SQL].
+func (s *statement) bindings() []any {
+	bindings := make([]any, 0, s.columnCount())
+	s.eachColumn(func(col *column, table string) {
+		bindings = append(bindings, col.dest)
+	})
+	return bindings
+}
+
+// fields returns the field paths of the statement columns, including those of its joins, in the order that the
+// columns are written by [statement.SQL].
+func (s *statement) fields() []string {
+	fields := make([]string, 0, s.columnCount())
+	s.eachColumn(func(col *column, table string) {
+		fields = append(fields, col.path)
+	})
+	return fields
+}
+
+// eachColumn calls the supplied function with each column of the statement and its joins, along with the table of
+// the column, in the order that the columns are written by [statement.SQL].
+func (s *statement) eachColumn(fn func(col *column, table string)) {
+	for i := range s.columns {
+		fn(&s.columns[i], s.table)
 	}
-	if s.hasConditions() {
-		query.WriteString(" WHERE ")
-		s.writeConditions(&query, 0)
+	for i := range s.joins {
+		s.joins[i].eachColumn(fn)
 	}
-	if s.hasGroup() {
-		query.WriteString(" GROUP BY ")
-		s.writeGroup(&query, 0)
-	}
-	if s.hasOrder() {
-		query.WriteString(" ORDER BY ")
-		s.writeOrder(&query, 0)
+}
+
+// eachStatement calls the supplied function with the statement and each of its joins, in the order that the joins are
+// written by [statement.SQL].
+func (s *statement) eachStatement(fn func(s *statement)) {
+	fn(s)
+	for i := range s.joins {
+		s.joins[i].eachStatement(fn)
 	}
-	if s.limit != "" {
-		query.WriteString(" LIMIT ")
-		query.WriteString(s.limit)
+}
+
+// alias assigns an alias to each column of the statement and its joins which does not define its own alias. The
+// aliases are numbered by the position of the column (e.g. c0, c1).
+func (s *statement) alias() {
+	n := 0
+	s.eachColumn(func(col *column, table string) {
+		if _, ok := col.definedAlias(); !ok {
+			col.alias = "c" + strconv.Itoa(n)
+		}
+		n++
+	})
+}
+
+// verify returns an error if the supplied result set column names do not match the names of the columns of the
+// statement. The error identifies the struct field of the first mismatched column.
+func (s *statement) verify(names []string) error {
+	var (
+		err error
+		i   int
+	)
+	s.eachColumn(func(col *column, table string) {
+		switch {
+		case err != nil:
+		case i >= len(names):
+			err = fmt.Errorf("missing result column %q for field %s", col.key(), col.path)
+		case !strings.EqualFold(names[i], col.key()):
+			err = fmt.Errorf("result column %q does not match column %q for field %s", names[i], col.key(), col.path)
+		}
+		i++
+	})
+	if err == nil && i < len(names) {
+		err = fmt.Errorf("unexpected result column %q", names[i])
 	}
-	if s.offset != "" {
-		query.WriteString(" OFFSET ")
-		query.WriteString(s.offset)
+	return err
+}
+
+// match returns scan destinations for the supplied result set column names. Each result column is bound to the
+// first unbound column of the statement, in the order that the columns are written by [statement.SQL], with a
+// matching name or table qualified name. Result columns without a matching statement column are discarded. The
+// field paths of the bound columns are returned alongside the destinations.
+func (s *statement) match(names []string) ([]any, []string) {
+	type candidate struct {
+		column
+		table string
+		bound bool
 	}
+	var candidates []candidate
+	s.eachColumn(func(col *column, table string) {
+		candidates = append(candidates, candidate{column: *col, table: table})
+	})
 
-	return query.String()
+	bindings := make([]any, len(names))
+	fields := make([]string, len(names))
+	for i, name := range names {
+		bindings[i] = new(any)
+		for j := range candidates {
+			c := &candidates[j]
+			if !c.bound && (strings.EqualFold(name, c.key()) || strings.EqualFold(name, c.expr(c.table))) {
+				bindings[i], fields[i] = c.dest, c.path
+				c.bound = true
+				break
+			}
+		}
+	}
+	return bindings, fields
 }
 
-func (s *statement) writeColumns(w *strings.Builder, i int) {
+// column returns the SQL expression of the column identified by the supplied key. A key identifies a column by its
+// name, its table qualified name, or the unqualified name of a column defined with a table qualified tag. Columns
+// defined by expressions other than a column reference cannot be identified. Columns of the statement take precedence
+// over columns of its joins. The field index of the column is returned along with the expression.
+func (s *statement) column(key string) (string, []int, bool) {
 	for _, col := range s.columns {
-		if i > 0 {
-			w.WriteString(", ")
+		if !isReference(col.name) {
+			continue
 		}
-		if col.useTable {
-			w.WriteString(s.table)
-			w.WriteByte('.')
+		expr := col.expr(s.table)
+		if key == col.name || key == expr || key == expr[strings.LastIndexByte(expr, '.')+1:] {
+			return expr, col.field, true
 		}
-		w.WriteString(col.name)
-		i++
 	}
 	for _, join := range s.joins {
-		join.writeColumns(w, i)
+		if expr, field, ok := join.column(key); ok {
+			return expr, field, true
+		}
 	}
+	return "", nil, false
 }
 
-func (s *statement) writeJoin(w *strings.Builder) {
-	switch s.join {
-	case joinNone:
-		return
-	case joinInner:
-		w.WriteString(" INNER JOIN ")
-	case joinLeft:
-		w.WriteString(" LEFT JOIN ")
+// index prefixes the field index and path of the statement columns, including those of its joins, with the supplied
+// struct field index and name. A negative index removes the field index for columns that cannot be addressed from
+// the parent struct.
+func (s *statement) index(i int, name string) {
+	s.eachColumn(func(col *column, table string) {
+		if col.path == "" {
+			col.path = name
+		} else {
+			col.path = name + "." + col.path
+		}
+		if i < 0 || col.field == nil {
+			col.field = nil
+			return
+		}
+		col.field = append([]int{i}, col.field...)
+	})
+}
+
+// isReference returns true if the supplied column name is a plain, optionally table qualified, column reference.
+func isReference(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] != '.' && !isIdent(name[i], false) {
+			return false
+		}
 	}
-	w.WriteString(s.table)
-	w.WriteString(" ON ")
-	w.WriteString(s.on)
+	return true
+}
 
-	for _, join := range s.joins {
-		join.writeJoin(w)
+// walk calls the supplied function with each expression of the statement in the order that the expressions are
+// written by [statement.SQL]. The function may modify the expression.
+func (s *statement) walk(fn func(expr *string)) {
+	s.walkColumns(fn)
+	for i := range s.joins {
+		s.joins[i].walkJoin(fn)
 	}
+	s.walkConditions(fn)
+	s.walkGroup(fn)
+	s.walkHaving(fn)
+	s.walkOrder(fn)
+	fn(&s.limit)
+	fn(&s.offset)
 }
 
-func (s *statement) hasConditions() bool {
-	for _, s := range s.joins {
-		if s.hasConditions() {
-			return true
-		}
+func (s *statement) walkColumns(fn func(expr *string)) {
+	for i := range s.columns {
+		fn(&s.columns[i].name)
+	}
+	for i := range s.joins {
+		s.joins[i].walkColumns(fn)
 	}
-	return len(s.conditions) > 0
 }
 
-func (s *statement) writeConditions(query *strings.Builder, depth int) {
-	for i, condition := range s.conditions {
-		if i > 0 {
-			query.WriteString(" AND ")
-		}
-		query.WriteByte('(')
-		query.WriteString(condition)
-		query.WriteByte(')')
+func (s *statement) walkJoin(fn func(expr *string)) {
+	fn(&s.on)
+	for i := range s.joins {
+		s.joins[i].walkJoin(fn)
 	}
-	for _, join := range s.joins {
-		join.writeConditions(query, depth+1)
+}
+
+func (s *statement) walkConditions(fn func(expr *string)) {
+	for i := range s.conditions {
+		s.conditions[i].walk(fn)
+	}
+	for i := range s.joins {
+		s.joins[i].walkConditions(fn)
 	}
 }
 
-func (s *statement) hasGroup() bool {
-	for _, s := range s.joins {
-		if s.hasGroup() {
-			return true
+func (s *statement) walkGroup(fn func(expr *string)) {
+	for i := range s.group {
+		fn(&s.group[i])
+	}
+	for i := range s.joins {
+		s.joins[i].walkGroup(fn)
+	}
+}
+
+func (s *statement) walkHaving(fn func(expr *string)) {
+	for i := range s.having {
+		s.having[i].walk(fn)
+	}
+	for i := range s.joins {
+		s.joins[i].walkHaving(fn)
+	}
+}
+
+func (s *statement) walkOrder(fn func(expr *string)) {
+	for i := range s.order {
+		fn(&s.order[i])
+	}
+	for i := range s.joins {
+		s.joins[i].walkOrder(fn)
+	}
+}
+
+// count returns a statement which counts the rows matched by the statement using the supplied expression. The
+// columns, ordering, limit and offset of the statement are removed. Grouped statements are counted using a subquery.
+func (s statement) count(expr string) statement {
+	s = s.strip()
+	if s.hasGroup() {
+		s.columns = []column{{name: "1"}}
+		return statement{
+			columns: []column{{name: "COUNT(*)"}},
+			table:   "(" + s.SQL() + ") AS counted",
 		}
 	}
-	return len(s.group) > 0
+	s.columns = []column{{name: expr}}
+	return s
+}
+
+// exists returns a statement which tests whether the statement matches any rows. The columns, ordering, limit and
+// offset of the statement are removed.
+func (s statement) exists() statement {
+	s = s.strip()
+	s.columns = []column{{name: "1"}}
+	return statement{columns: []column{{name: "EXISTS(" + s.SQL() + ")"}}}
 }
 
-func (s *statement) writeGroup(query *strings.Builder, elements int) {
-	for _, group := range s.group {
-		if elements > 0 {
-			query.WriteString(", ")
+// only returns a copy of the statement with the columns of the statement and its joins removed, except for the
+// column scanned into the struct field identified by the supplied index.
+func (s statement) only(field []int) statement {
+	columns := make([]column, 0, 1)
+	for _, col := range s.columns {
+		if equalIndex(col.field, field) {
+			columns = append(columns, col)
 		}
-		query.WriteString(group)
-		elements++
 	}
-	for _, join := range s.joins {
-		join.writeGroup(query, elements)
+	s.columns = columns
+	joins := make([]statement, len(s.joins))
+	for i, join := range s.joins {
+		joins[i] = join.only(field)
 	}
+	s.joins = joins
+	return s
 }
 
-func (s *statement) hasOrder() bool {
-	for _, s := range s.joins {
-		if s.hasOrder() {
-			return true
+// equalIndex returns true if the supplied struct field index sequences are equal and not empty.
+func equalIndex(a, b []int) bool {
+	if len(a) == 0 || len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
 		}
 	}
-	return len(s.order) > 0
+	return true
+}
+
+// columnCount returns the number of columns selected by the statement and its joins.
+func (s *statement) columnCount() int {
+	n := len(s.columns)
+	for i := range s.joins {
+		n += s.joins[i].columnCount()
+	}
+	return n
+}
+
+// strip returns a copy of the statement with the columns, ordering, limit and offset removed from the statement and
+// its joins.
+func (s statement) strip() statement {
+	s.columns = nil
+	s.order = nil
+	s.limit = ""
+	s.offset = ""
+	joins := make([]statement, len(s.joins))
+	for i, join := range s.joins {
+		joins[i] = join.strip()
+	}
+	s.joins = joins
+	return s
 }
 
-func (s *statement) writeOrder(query *strings.Builder, elements int) {
-	for _, order := range s.order {
-		if elements > 0 {
-			query.WriteString(", ")
+// prune removes the optional conditions that reference named arguments which are not present from the statement and
+// its joins.
+func (s *statement) prune(present func(name string) bool) {
+	conditions := make([]condition, 0, len(s.conditions))
+	for _, c := range s.conditions {
+		if c, ok := c.prune(present); ok {
+			conditions = append(conditions, c)
 		}
-		query.WriteString(order)
-		elements++
 	}
-	for _, join := range s.joins {
-		join.writeOrder(query, elements)
+	s.conditions = conditions
+
+	joins := make([]statement, len(s.joins))
+	for i, join := range s.joins {
+		join.prune(present)
+		joins[i] = join
 	}
+	s.joins = joins
+}
+
+func (s *statement) hasGroup() bool {
+	for _, s := range s.joins {
+		if s.hasGroup() {
+			return true
+		}
+	}
+	return len(s.group) > 0
 }
@@ -0,0 +1,253 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Statement represents the properties of a query prior to being rendered to SQL. Joined query structs are flattened
+// into the statement: the columns, conditions, grouping and ordering of joined tables follow those of the table that
+// joins them, in the order they are written to the query. A Statement may be modified by the [Options] Rewrite
+// function before the query is produced. Example:
+//
+//	// Query: SELECT id, name FROM users INNER JOIN addresses ON users.address_id = addresses.id WHERE (id = ?)
+//	stmt := query.Statement{
+//		Columns:    []query.ColumnExpr{{Expr: "id"}, {Expr: "name"}},
+//		From:       "users",
+//		Joins:      []query.Join{{Table: "addresses", On: "users.address_id = addresses.id"}},
+//		Conditions: []query.Condition{{Expr: "id = ?"}},
+//	}
+type Statement struct {
+	Columns    []ColumnExpr
+	From       string
+	Joins      []Join
+	Conditions []Condition
+	Group      []string
+	Having     []Condition
+	Order      []string
+	Limit      string
+	Offset     string
+}
+
+// ColumnExpr identifies a select column of a [Statement]. The Expr holds the SQL expression of the column, qualified
+// by its table where inferred from a struct field name. If an Alias is assigned, the column is selected using the
+// alias. Columns are scanned into the query struct in order; columns must not be added, removed or reordered by a
+// rewrite.
+type ColumnExpr struct {
+	Expr  string
+	Alias string
+
+	field []int
+	path  string
+	dest  any
+}
+
+// Join identifies a table joined to a [Statement]. The table is joined using an INNER JOIN, or a LEFT JOIN if Left
+// is set, on the condition held by On.
+type Join struct {
+	Table string
+	On    string
+	Left  bool
+}
+
+// Condition identifies an expression of the WHERE or HAVING sections of a [Statement]. A condition is either a single
+// expression held by Expr, or a group of branch conditions joined using AND, or OR if Any is set. Each expression and
+// group is parenthesized to ensure that conditions are evaluated in the order defined by the query structure.
+//
+// Like [Where], the positional placeholders of an expression added by a rewrite are bound to its Args, independent of
+// the arguments of the query, while named parameters are bound to the named arguments of the query. Conditions of the
+// query struct do not hold Args as they are bound to the arguments of the query.
+type Condition struct {
+	Expr     string
+	Args     []any
+	Any      bool
+	Branches []Condition
+}
+
+// SQL returns the query specified by the statement.
+func (s *Statement) SQL() string {
+	var query strings.Builder
+	query.WriteString("SELECT ")
+	for i, col := range s.Columns {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString(col.Expr)
+		if col.Alias != "" {
+			query.WriteString(" AS ")
+			query.WriteString(col.Alias)
+		}
+	}
+	if s.From != "" {
+		query.WriteString(" FROM ")
+		query.WriteString(s.From)
+	}
+	for _, join := range s.Joins {
+		if join.Left {
+			query.WriteString(" LEFT JOIN ")
+		} else {
+			query.WriteString(" INNER JOIN ")
+		}
+		query.WriteString(join.Table)
+		query.WriteString(" ON ")
+		query.WriteString(join.On)
+	}
+	if len(s.Conditions) > 0 {
+		query.WriteString(" WHERE ")
+		writeConditions(&query, s.Conditions, " AND ")
+	}
+	if len(s.Group) > 0 {
+		query.WriteString(" GROUP BY ")
+		query.WriteString(strings.Join(s.Group, ", "))
+	}
+	if len(s.Having) > 0 {
+		query.WriteString(" HAVING ")
+		writeConditions(&query, s.Having, " AND ")
+	}
+	if len(s.Order) > 0 {
+		query.WriteString(" ORDER BY ")
+		query.WriteString(strings.Join(s.Order, ", "))
+	}
+	if s.Limit != "" {
+		query.WriteString(" LIMIT ")
+		query.WriteString(s.Limit)
+	}
+	if s.Offset != "" {
+		query.WriteString(" OFFSET ")
+		query.WriteString(s.Offset)
+	}
+
+	return query.String()
+}
+
+// write writes the condition to the supplied builder.
+func (c *Condition) write(w *strings.Builder) {
+	switch {
+	case c.Expr != "" || len(c.Branches) == 0:
+		w.WriteByte('(')
+		w.WriteString(c.Expr)
+		w.WriteByte(')')
+	case len(c.Branches) == 1:
+		c.Branches[0].write(w)
+	case c.Any:
+		w.WriteByte('(')
+		writeConditions(w, c.Branches, " OR ")
+		w.WriteByte(')')
+	default:
+		w.WriteByte('(')
+		writeConditions(w, c.Branches, " AND ")
+		w.WriteByte(')')
+	}
+}
+
+// writeConditions writes the supplied conditions to the builder separated by the operator.
+func writeConditions(w *strings.Builder, conditions []Condition, operator string) {
+	for i := range conditions {
+		if i > 0 {
+			w.WriteString(operator)
+		}
+		conditions[i].write(w)
+	}
+}
+
+// export returns the [Statement] described by the statement and its joins.
+func (s *statement) export() Statement {
+	st := Statement{From: s.table, Limit: s.limit, Offset: s.offset}
+	s.eachColumn(func(col *column, table string) {
+		st.Columns = append(st.Columns, ColumnExpr{
+			Expr:  col.expr(table),
+			Alias: col.alias,
+			field: col.field,
+			path:  col.path,
+			dest:  col.dest,
+		})
+	})
+	s.eachStatement(func(s *statement) {
+		if s.join != joinNone {
+			st.Joins = append(st.Joins, Join{Table: s.table, On: s.on, Left: s.join == joinLeft})
+		}
+		st.Conditions = appendConditions(st.Conditions, s.conditions)
+		st.Group = append(st.Group, s.group...)
+		st.Having = appendConditions(st.Having, s.having)
+		st.Order = append(st.Order, s.order...)
+	})
+	return st
+}
+
+// appendConditions appends the exported form of the non-empty conditions to the supplied conditions.
+func appendConditions(dst []Condition, conditions []condition) []Condition {
+	for _, c := range conditions {
+		if c.empty() {
+			continue
+		}
+		dst = append(dst, c.export())
+	}
+	return dst
+}
+
+// export returns the [Condition] described by the condition.
+func (c condition) export() Condition {
+	if c.operator == "" {
+		return Condition{Expr: c.expr}
+	}
+	return Condition{Any: c.operator == operatorOr, Branches: appendConditions(nil, c.branches)}
+}
+
+// statement returns the statement described by the [Statement], along with the named arguments binding the Args of
+// its conditions. The joins of the returned statement do not define columns or conditions of their own.
+func (s *Statement) statement() (statement, []any) {
+	var im importer
+	stmt := statement{
+		columns:    make([]column, len(s.Columns)),
+		table:      s.From,
+		conditions: im.conditions(s.Conditions),
+		group:      s.Group,
+		having:     im.conditions(s.Having),
+		order:      s.Order,
+		limit:      s.Limit,
+		offset:     s.Offset,
+	}
+	for i, col := range s.Columns {
+		stmt.columns[i] = column{name: col.Expr, field: col.field, path: col.path, dest: col.dest, alias: col.Alias}
+	}
+	for _, join := range s.Joins {
+		j := statement{table: join.Table, join: joinInner, on: join.On}
+		if join.Left {
+			j.join = joinLeft
+		}
+		stmt.joins = append(stmt.joins, j)
+	}
+	return stmt, im.args
+}
+
+// importer collects the named arguments binding the Args of imported conditions.
+type importer struct {
+	args []any
+	n    int
+}
+
+// conditions returns the conditions described by the supplied [Condition] values.
+func (im *importer) conditions(conditions []Condition) []condition {
+	if len(conditions) == 0 {
+		return nil
+	}
+	imported := make([]condition, len(conditions))
+	for i, c := range conditions {
+		switch {
+		case c.Expr != "" || len(c.Branches) == 0:
+			expr := c.Expr
+			if len(c.Args) > 0 {
+				var named []any
+				im.n++
+				expr, named = bindExpr(expr, c.Args, fmt.Sprintf("_rewrite%d", im.n))
+				im.args = append(im.args, named...)
+			}
+			imported[i] = condition{expr: expr}
+		case c.Any:
+			imported[i] = condition{operator: operatorOr, branches: im.conditions(c.Branches)}
+		default:
+			imported[i] = condition{operator: operatorAnd, branches: im.conditions(c.Branches)}
+		}
+	}
+	return imported
+}
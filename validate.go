@@ -0,0 +1,106 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Validate checks that the Source type can be used to build a query and reports every problem found, rather than
+// only the first. The query struct is prepared as it is by [All], also checking for unsupported field types, markers
+// defined more than once, many relationships and pointer joins without an identity column and conflicting [Table]
+// markers of embedded structs. The placeholders of the query are checked for mixed positional styles and ordinal
+// placeholders which leave arguments unreferenced. The default namer is used if the namer is nil. A
+// *[ValidationError] holding a *[StructError] for each problem is returned if the Source type is invalid. Validate is
+// intended to be called from an init function or a test. Example:
+//
+//	func TestQueries(t *testing.T) {
+//		if err := query.Validate[users](nil); err != nil {
+//			t.Error(err)
+//		}
+//	}
+func Validate[Source any](namer Namer) error {
+	if namer == nil {
+		namer = defaultNamer
+	}
+	var results []Source
+	stmt, _, err := preparer{namer: namer, validate: true}.prepareSet(&results)
+	if err != nil {
+		return &ValidationError{Errors: problems(err)}
+	}
+
+	typ := reflect.TypeOf((*Source)(nil)).Elem()
+	if errs := checkPlaceholders(typ, stmt.SQL()); len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// singleMarkers identifies the markers which may only be defined once by a struct.
+var singleMarkers = map[reflect.Type]bool{
+	reflect.TypeOf(Table{}):    true,
+	reflect.TypeOf(Limit{}):    true,
+	reflect.TypeOf(Offset{}):   true,
+	reflect.TypeOf(LeftJoin{}): true,
+}
+
+// pathName returns the supplied field path, or a description of the query struct if the path is empty.
+func pathName(path string) string {
+	if path == "" {
+		return "the query struct"
+	}
+	return path
+}
+
+// checkPlaceholders returns the problems with the placeholders of the supplied query, reported against the query
+// struct of the supplied type.
+func checkPlaceholders(typ reflect.Type, query string) []error {
+	var (
+		errs       []error
+		question   bool
+		referenced = make(map[int]bool)
+		ordinals   int
+	)
+	for _, p := range placeholders(query) {
+		switch {
+		case p.name != "":
+		case p.ordinal > 0:
+			referenced[p.ordinal] = true
+			if p.ordinal > ordinals {
+				ordinals = p.ordinal
+			}
+		default:
+			question = true
+		}
+	}
+	if question && ordinals > 0 {
+		errs = append(errs, &StructError{Type: typ,
+			Err: fmt.Errorf("%w: query mixes ? and $n placeholders", ErrPlaceholder)})
+	}
+	for i := 1; i <= ordinals; i++ {
+		if !referenced[i] {
+			errs = append(errs, &StructError{Type: typ,
+				Err: fmt.Errorf("%w: $%d is not referenced by the query", ErrPlaceholder, i)})
+		}
+	}
+	return errs
+}
+
+// scannable returns true if values of the supplied type can be scanned into by [sql.Rows.Scan].
+func scannable(typ reflect.Type) bool {
+	if reflect.PointerTo(typ).Implements(scannerType) || isBytes(typ) {
+		return true
+	}
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return scannable(typ.Elem())
+	case reflect.Bool, reflect.String, reflect.Interface,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Struct:
+		return typ == reflect.TypeOf(time.Time{})
+	}
+	return false
+}